@@ -0,0 +1,193 @@
+package Netpbm
+
+import "math"
+
+// gaussianBlur returns the filter blurring a plane with a Gaussian of the given
+// sigma, the kernel being cut at 3 sigma
+func gaussianBlur(sigma float64) func([][]float64) [][]float64 {
+	return func(plane [][]float64) [][]float64 {
+		if sigma <= 0 {
+			return plane
+		}
+		k := gaussianKernel(sigma, int(math.Ceil(3*sigma)))
+		return convolveSeparable(plane, k, k, BorderClamp)
+	}
+}
+
+
+// boxBlur returns the filter averaging every pixel with its neighbours up to
+// radius pixels away
+func boxBlur(radius int) func([][]float64) [][]float64 {
+	return func(plane [][]float64) [][]float64 {
+		if radius <= 0 {
+			return plane
+		}
+		k := make([]float64, 2*radius+1)
+		for i := range k {
+			k[i] = 1 / float64(len(k))
+		}
+		return convolveSeparable(plane, k, k, BorderClamp)
+	}
+}
+
+
+// motionKernel returns the kernel smearing pixels along a segment of the given
+// length, at angle degrees counter-clockwise from the horizontal
+func motionKernel(length int, angle float64) [][]float64 {
+	radius := length / 2
+	size := 2*radius + 1
+	kernel := make([][]float64, size)
+	for y := range kernel {
+		kernel[y] = make([]float64, size)
+	}
+	dx, dy := math.Cos(angle*math.Pi/180), -math.Sin(angle*math.Pi/180)
+	// sample the segment finely, spreading every sample on its 4 nearest cells
+	steps := 4 * length
+	for i := 0; i <= steps; i++ {
+		t := (float64(i)/float64(steps) - 0.5) * float64(length-1)
+		x, y := float64(radius)+t*dx, float64(radius)+t*dy
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		for _, c := range [4]struct {
+			x, y int
+			w    float64
+		}{
+			{int(x0), int(y0), (1 - fx) * (1 - fy)},
+			{int(x0) + 1, int(y0), fx * (1 - fy)},
+			{int(x0), int(y0) + 1, (1 - fx) * fy},
+			{int(x0) + 1, int(y0) + 1, fx * fy},
+		} {
+			if c.x >= 0 && c.x < size && c.y >= 0 && c.y < size {
+				kernel[c.y][c.x] += c.w
+			}
+		}
+	}
+	total := 0.0
+	for _, r := range kernel {
+		for _, v := range r {
+			total += v
+		}
+	}
+	for _, r := range kernel {
+		for x := range r {
+			r[x] /= total
+		}
+	}
+	return kernel
+}
+
+
+// sharpenKernel returns the kernel adding amount times the difference between
+// a pixel and its four neighbours
+func sharpenKernel(amount float64) [][]float64 {
+	return [][]float64{
+		{0, -amount, 0},
+		{-amount, 1 + 4*amount, -amount},
+		{0, -amount, 0},
+	}
+}
+
+
+// unsharpMask returns the filter adding amount times the difference between a
+// plane and its Gaussian blur of sigma radius, where that difference is at
+// least threshold
+func unsharpMask(amount, radius, threshold float64) func([][]float64) [][]float64 {
+	return func(plane [][]float64) [][]float64 {
+		blurred := gaussianBlur(radius)(plane)
+		out := make([][]float64, len(plane))
+		for y, r := range plane {
+			out[y] = make([]float64, len(r))
+			for x, v := range r {
+				out[y][x] = v
+				if d := v - blurred[y][x]; math.Abs(d) >= threshold {
+					out[y][x] += amount * d
+				}
+			}
+		}
+		return out
+	}
+}
+
+
+// GaussianBlur blurs the graymap with a Gaussian of standard deviation sigma,
+// in pixels.
+func (pgm *PGM) GaussianBlur(sigma float64) {
+	pgm.mapPlane(gaussianBlur(sigma))
+}
+
+
+// BoxBlur replaces every pixel by the mean of the square of side 2*radius+1
+// around it.
+func (pgm *PGM) BoxBlur(radius int) {
+	pgm.mapPlane(boxBlur(radius))
+}
+
+
+// MotionBlur smears the graymap along a line of length pixels, at angle
+// degrees counter-clockwise from the horizontal, as if it moved while shot.
+func (pgm *PGM) MotionBlur(length int, angle float64) {
+	if length > 1 {
+		pgm.mapPlane(func(plane [][]float64) [][]float64 {
+			return convolvePlane(plane, motionKernel(length, angle), BorderClamp)
+		})
+	}
+}
+
+
+// Sharpen strengthens the edges, amount 1 giving the classic 3x3 sharpening
+// kernel.
+func (pgm *PGM) Sharpen(amount float64) {
+	pgm.mapPlane(func(plane [][]float64) [][]float64 {
+		return convolvePlane(plane, sharpenKernel(amount), BorderClamp)
+	})
+}
+
+
+// UnsharpMask sharpens by adding amount times the details removed by a
+// Gaussian blur of the given radius (sigma, in pixels). Details smaller than
+// threshold samples are left alone, so noise is not amplified.
+func (pgm *PGM) UnsharpMask(amount, radius, threshold float64) {
+	pgm.mapPlane(unsharpMask(amount, radius, threshold))
+}
+
+
+// GaussianBlur blurs every channel with a Gaussian of standard deviation
+// sigma, in pixels.
+func (ppm *PPM) GaussianBlur(sigma float64) {
+	ppm.mapPlanes(gaussianBlur(sigma))
+}
+
+
+// BoxBlur replaces every pixel by the mean of the square of side 2*radius+1
+// around it.
+func (ppm *PPM) BoxBlur(radius int) {
+	ppm.mapPlanes(boxBlur(radius))
+}
+
+
+// MotionBlur smears the pixmap along a line of length pixels, at angle degrees
+// counter-clockwise from the horizontal.
+func (ppm *PPM) MotionBlur(length int, angle float64) {
+	if length > 1 {
+		kernel := motionKernel(length, angle)
+		ppm.mapPlanes(func(plane [][]float64) [][]float64 {
+			return convolvePlane(plane, kernel, BorderClamp)
+		})
+	}
+}
+
+
+// Sharpen strengthens the edges, amount 1 giving the classic 3x3 sharpening
+// kernel.
+func (ppm *PPM) Sharpen(amount float64) {
+	ppm.mapPlanes(func(plane [][]float64) [][]float64 {
+		return convolvePlane(plane, sharpenKernel(amount), BorderClamp)
+	})
+}
+
+
+// UnsharpMask sharpens every channel by adding amount times the details
+// removed by a Gaussian blur of the given radius. See PGM.UnsharpMask.
+func (ppm *PPM) UnsharpMask(amount, radius, threshold float64) {
+	ppm.mapPlanes(unsharpMask(amount, radius, threshold))
+}
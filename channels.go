@@ -0,0 +1,94 @@
+package Netpbm
+
+import "errors"
+
+// setChannel changes the value of channel c
+func (p *Pixel) setChannel(c Channel, value uint8) {
+	switch c {
+	case Green:
+		p.G = value
+	case Blue:
+		p.B = value
+	default:
+		p.R = value
+	}
+}
+
+
+// Channel returns channel c of the pixmap as a graymap with the same maxval.
+func (ppm *PPM) Channel(c Channel) *PGM {
+	pgm := NewPGM(ppm.width, ppm.height, ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pgm.data[y][x] = ppm.data[y][x].channel(c)
+		}
+	}
+	return pgm
+}
+
+
+// SplitChannels returns the red, green and blue planes of the pixmap as
+// graymaps, so they can be processed separately.
+func (ppm *PPM) SplitChannels() (*PGM, *PGM, *PGM) {
+	return ppm.Channel(Red), ppm.Channel(Green), ppm.Channel(Blue)
+}
+
+
+// MergeChannels builds a pixmap from its red, green and blue planes, which
+// must have the same size and maxval.
+func MergeChannels(r, g, b *PGM) (*PPM, error) {
+	if r.width != g.width || r.width != b.width || r.height != g.height || r.height != b.height {
+		return nil, errors.New("channels do not have the same size")
+	}
+	if r.max != g.max || r.max != b.max {
+		return nil, errors.New("channels do not have the same maxval")
+	}
+	ppm := NewPPM(r.width, r.height, r.max)
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			ppm.data[y][x] = Pixel{r.data[y][x], g.data[y][x], b.data[y][x]}
+		}
+	}
+	return ppm, nil
+}
+
+
+// SetChannel replaces channel c by the graymap plane, which must have the size
+// and maxval of the pixmap.
+func (ppm *PPM) SetChannel(c Channel, plane *PGM) error {
+	if plane.width != ppm.width || plane.height != ppm.height {
+		return errors.New("channel does not have the size of the image")
+	}
+	if plane.max != ppm.max {
+		return errors.New("channel does not have the maxval of the image")
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x].setChannel(c, plane.data[y][x])
+		}
+	}
+	return nil
+}
+
+
+// Swizzle reorders the channels: the new red, green and blue take the old
+// channels r, g and b. For instance Swizzle(Blue, Green, Red) swaps red and
+// blue, and Swizzle(Green, Green, Green) makes a gray image of the green.
+func (ppm *PPM) Swizzle(r, g, b Channel) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			ppm.data[y][x] = Pixel{p.channel(r), p.channel(g), p.channel(b)}
+		}
+	}
+}
+
+
+// FillChannel sets channel c to value on every pixel.
+func (ppm *PPM) FillChannel(c Channel, value uint8) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x].setChannel(c, value)
+		}
+	}
+}
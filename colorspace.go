@@ -0,0 +1,361 @@
+package Netpbm
+
+import "math"
+
+// Pixels are gamma-encoded sRGB samples going from 0 to the maxval of their
+// image, so every conversion takes that maxval.
+
+// HSV is a colour as hue (degrees, 0 to 360), saturation and value (0 to 1).
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL is a colour as hue (degrees, 0 to 360), saturation and lightness (0 to 1).
+type HSL struct {
+	H, S, L float64
+}
+
+// YCbCr is a full range BT.601 colour, as used by JPEG. Y goes from 0 to 1,
+// Cb and Cr from -0.5 to 0.5.
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// XYZ is a CIE 1931 colour computed from linear sRGB, Y being 1 for white (D65).
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab is a CIELAB colour relative to the D65 white point. L goes from 0 to 100.
+type Lab struct {
+	L, A, B float64
+}
+
+
+// ColorSpace lists the colour types a Pixel can be converted to.
+type ColorSpace interface {
+	HSV | HSL | YCbCr | XYZ | Lab
+}
+
+
+// D65 reference white
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+
+// linearize converts a gamma-encoded sRGB value in [0, 1] to linear light
+func linearize(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+
+// delinearize converts a linear light value in [0, 1] to gamma-encoded sRGB
+func delinearize(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+
+// normalize returns the channels of p scaled to [0, 1]
+func (p Pixel) normalize(max int) (float64, float64, float64) {
+	m := float64(max)
+	return float64(p.R) / m, float64(p.G) / m, float64(p.B) / m
+}
+
+
+// sample converts a value in [0, 1] to a sample for maxval max, clamping it
+func sample(v float64, max int) uint8 {
+	return uint8(math.Round(math.Min(math.Max(v, 0), 1) * float64(max)))
+}
+
+
+func pixelFrom(r, g, b float64, max int) Pixel {
+	return Pixel{sample(r, max), sample(g, max), sample(b, max)}
+}
+
+
+// hue returns the hue in degrees of normalized channels, and their max and min
+func hue(r, g, b float64) (h, hi, lo float64) {
+	hi = math.Max(r, math.Max(g, b))
+	lo = math.Min(r, math.Min(g, b))
+	d := hi - lo
+	switch {
+	case d == 0:
+		h = 0
+	case hi == r:
+		h = 60 * math.Mod((g-b)/d, 6)
+	case hi == g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, hi, lo
+}
+
+
+// fromHue builds normalized channels from a hue, a chroma and the amount m
+// added to every channel
+func fromHue(h, chroma, m float64) (float64, float64, float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = chroma, x, 0
+	case h < 120:
+		r, g, b = x, chroma, 0
+	case h < 180:
+		r, g, b = 0, chroma, x
+	case h < 240:
+		r, g, b = 0, x, chroma
+	case h < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+
+// ToHSV converts the pixel of an image with maxval max to HSV.
+func (p Pixel) ToHSV(max int) HSV {
+	h, hi, lo := hue(p.normalize(max))
+	s := 0.0
+	if hi > 0 {
+		s = (hi - lo) / hi
+	}
+	return HSV{h, s, hi}
+}
+
+
+// ToPixel converts the colour to a pixel for maxval max.
+func (c HSV) ToPixel(max int) Pixel {
+	chroma := c.V * c.S
+	r, g, b := fromHue(c.H, chroma, c.V-chroma)
+	return pixelFrom(r, g, b, max)
+}
+
+
+// ToHSL converts the pixel of an image with maxval max to HSL.
+func (p Pixel) ToHSL(max int) HSL {
+	h, hi, lo := hue(p.normalize(max))
+	l := (hi + lo) / 2
+	s := 0.0
+	if d := hi - lo; d > 0 {
+		s = d / (1 - math.Abs(2*l-1))
+	}
+	return HSL{h, s, l}
+}
+
+
+// ToPixel converts the colour to a pixel for maxval max.
+func (c HSL) ToPixel(max int) Pixel {
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	r, g, b := fromHue(c.H, chroma, c.L-chroma/2)
+	return pixelFrom(r, g, b, max)
+}
+
+
+// ToYCbCr converts the pixel of an image with maxval max to YCbCr.
+func (p Pixel) ToYCbCr(max int) YCbCr {
+	r, g, b := p.normalize(max)
+	return YCbCr{
+		Y:  0.299*r + 0.587*g + 0.114*b,
+		Cb: -0.168736*r - 0.331264*g + 0.5*b,
+		Cr: 0.5*r - 0.418688*g - 0.081312*b,
+	}
+}
+
+
+// ToPixel converts the colour to a pixel for maxval max.
+func (c YCbCr) ToPixel(max int) Pixel {
+	return pixelFrom(
+		c.Y+1.402*c.Cr,
+		c.Y-0.344136*c.Cb-0.714136*c.Cr,
+		c.Y+1.772*c.Cb,
+		max)
+}
+
+
+// ToXYZ converts the pixel of an image with maxval max to CIE XYZ.
+func (p Pixel) ToXYZ(max int) XYZ {
+	r, g, b := p.normalize(max)
+	r, g, b = linearize(r), linearize(g), linearize(b)
+	return XYZ{
+		X: 0.4124564*r + 0.3575761*g + 0.1804375*b,
+		Y: 0.2126729*r + 0.7151522*g + 0.0721750*b,
+		Z: 0.0193339*r + 0.1191920*g + 0.9503041*b,
+	}
+}
+
+
+// ToPixel converts the colour to a pixel for maxval max. Colours outside the
+// sRGB gamut are clamped.
+func (c XYZ) ToPixel(max int) Pixel {
+	r := 3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z
+	g := -0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z
+	b := 0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, 0), 1) }
+	return pixelFrom(delinearize(clamp(r)), delinearize(clamp(g)), delinearize(clamp(b)), max)
+}
+
+
+// ToLab converts the colour to CIELAB.
+func (c XYZ) ToLab() Lab {
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return t*24389/3132 + 4.0/29
+	}
+	fx, fy, fz := f(c.X/whiteX), f(c.Y/whiteY), f(c.Z/whiteZ)
+	return Lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+
+// ToXYZ converts the colour to CIE XYZ.
+func (c Lab) ToXYZ() XYZ {
+	finv := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return (t - 4.0/29) * 3132 / 24389
+	}
+	fy := (c.L + 16) / 116
+	return XYZ{whiteX * finv(fy+c.A/500), whiteY * finv(fy), whiteZ * finv(fy-c.B/200)}
+}
+
+
+// ToLab converts the pixel of an image with maxval max to CIELAB.
+func (p Pixel) ToLab(max int) Lab {
+	return p.ToXYZ(max).ToLab()
+}
+
+
+// ToPixel converts the colour to a pixel for maxval max.
+func (c Lab) ToPixel(max int) Pixel {
+	return c.ToXYZ().ToPixel(max)
+}
+
+
+// DeltaE returns the CIE76 distance between two colours, about 2.3 being the
+// smallest difference the eye can notice.
+func (c Lab) DeltaE(other Lab) float64 {
+	return math.Sqrt((c.L-other.L)*(c.L-other.L) + (c.A-other.A)*(c.A-other.A) + (c.B-other.B)*(c.B-other.B))
+}
+
+
+// toColorSpace converts a pixel to the colour space C
+func toColorSpace[C ColorSpace](p Pixel, max int) C {
+	var c C
+	switch c := any(&c).(type) {
+	case *HSV:
+		*c = p.ToHSV(max)
+	case *HSL:
+		*c = p.ToHSL(max)
+	case *YCbCr:
+		*c = p.ToYCbCr(max)
+	case *XYZ:
+		*c = p.ToXYZ(max)
+	case *Lab:
+		*c = p.ToLab(max)
+	}
+	return c
+}
+
+
+// fromColorSpace converts a colour of the space C back to a pixel
+func fromColorSpace[C ColorSpace](c C, max int) Pixel {
+	switch c := any(c).(type) {
+	case HSV:
+		return c.ToPixel(max)
+	case HSL:
+		return c.ToPixel(max)
+	case YCbCr:
+		return c.ToPixel(max)
+	case XYZ:
+		return c.ToPixel(max)
+	case Lab:
+		return c.ToPixel(max)
+	}
+	return Pixel{}
+}
+
+
+// ToColorSpace converts every pixel of ppm to the colour space C, for example
+// ToColorSpace[Lab](ppm).
+func ToColorSpace[C ColorSpace](ppm *PPM) [][]C {
+	data := make([][]C, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		data[y] = make([]C, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			data[y][x] = toColorSpace[C](ppm.data[y][x], ppm.max)
+		}
+	}
+	return data
+}
+
+
+// FromColorSpace builds a PPM with maxval max from colours of the space C.
+func FromColorSpace[C ColorSpace](data [][]C, max int) *PPM {
+	width := 0
+	if len(data) > 0 {
+		width = len(data[0])
+	}
+	ppm := NewPPM(width, len(data), max)
+	for y := range data {
+		for x := 0; x < width; x++ {
+			ppm.data[y][x] = fromColorSpace(data[y][x], max)
+		}
+	}
+	return ppm
+}
+
+
+// MapColorSpace replaces every pixel of ppm by f applied to its colour in the
+// space C. For instance, to darken in perceptual terms:
+//
+//	MapColorSpace(ppm, func(c Lab) Lab { c.L *= 0.8; return c })
+func MapColorSpace[C ColorSpace](ppm *PPM, f func(C) C) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = fromColorSpace(f(toColorSpace[C](ppm.data[y][x], ppm.max)), ppm.max)
+		}
+	}
+}
+
+
+// HueShift rotates the hue of every pixel by the given number of degrees.
+func (ppm *PPM) HueShift(degrees float64) {
+	MapColorSpace(ppm, func(c HSV) HSV {
+		c.H = math.Mod(c.H+degrees, 360)
+		if c.H < 0 {
+			c.H += 360
+		}
+		return c
+	})
+}
+
+
+// Saturate multiplies the saturation of every pixel by factor, 0 giving a gray
+// image.
+func (ppm *PPM) Saturate(factor float64) {
+	MapColorSpace(ppm, func(c HSL) HSL {
+		c.S = math.Min(c.S*factor, 1)
+		return c
+	})
+}
@@ -0,0 +1,166 @@
+package Netpbm
+
+// Connectivity tells which neighbours of a pixel touch it.
+type Connectivity int
+
+const (
+	Connect8 Connectivity = iota // the 8 surrounding pixels, diagonals included
+	Connect4                     // the pixels above, below, left and right only
+)
+
+
+// previous returns the offsets of the neighbours already visited by a scan
+// going left to right and top to bottom
+func (c Connectivity) previous() []Point {
+	if c == Connect4 {
+		return []Point{{-1, 0}, {0, -1}}
+	}
+	return []Point{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+}
+
+
+// Component describes a connected region of true pixels.
+type Component struct {
+	Label     int       // value of the region in the label map, from 1
+	Area      int       // number of pixels
+	Bounds    Rectangle // smallest rectangle holding the region
+	CentroidX float64   // mean position of the pixels
+	CentroidY float64
+	Perimeter int // number of pixel sides between the region and the background
+}
+
+
+// find returns the root of a label in a union-find forest, compressing the path
+func find(parent []int, label int) int {
+	for parent[label] != label {
+		parent[label] = parent[parent[label]]
+		label = parent[label]
+	}
+	return label
+}
+
+
+// Components labels the connected regions of true pixels of the bitmap. The
+// label map has the size of the bitmap, false pixels being 0 and the pixels of
+// a region the label of its Component, components being numbered from 1 in the
+// order of their first pixel.
+func (pbm *PBM) Components(conn Connectivity) ([][]int, []Component) {
+	labels := make([][]int, pbm.height)
+	for y := range labels {
+		labels[y] = make([]int, pbm.width)
+	}
+
+	// first pass: provisional labels, recording which ones touch
+	parent := []int{0}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] {
+				continue
+			}
+			label := 0
+			for _, d := range conn.previous() {
+				nx, ny := x+d.X, y+d.Y
+				if nx < 0 || nx >= pbm.width || ny < 0 {
+					continue
+				}
+				n := labels[ny][nx]
+				if n == 0 {
+					continue
+				}
+				if label == 0 {
+					label = find(parent, n)
+					continue
+				}
+				a, b := find(parent, label), find(parent, n)
+				if a < b {
+					parent[b] = a
+				} else {
+					parent[a] = b
+				}
+				label = find(parent, label)
+			}
+			if label == 0 {
+				label = len(parent)
+				parent = append(parent, label)
+			}
+			labels[y][x] = label
+		}
+	}
+
+	// second pass: final labels in scan order, and the statistics
+	final := make([]int, len(parent))
+	components := make([]Component, 0)
+	sumX, sumY := make([]int, 0), make([]int, 0)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if labels[y][x] == 0 {
+				continue
+			}
+			root := find(parent, labels[y][x])
+			if final[root] == 0 {
+				components = append(components, Component{Label: len(components) + 1, Bounds: Rectangle{x, y, 1, 1}})
+				sumX, sumY = append(sumX, 0), append(sumY, 0)
+				final[root] = len(components)
+			}
+			label := final[root]
+			labels[y][x] = label
+
+			c := &components[label-1]
+			c.Area++
+			sumX[label-1] += x
+			sumY[label-1] += y
+			if x < c.Bounds.X {
+				c.Bounds.Width += c.Bounds.X - x
+				c.Bounds.X = x
+			}
+			if x >= c.Bounds.X+c.Bounds.Width {
+				c.Bounds.Width = x - c.Bounds.X + 1
+			}
+			c.Bounds.Height = y - c.Bounds.Y + 1
+			for _, d := range []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				nx, ny := x+d.X, y+d.Y
+				if nx < 0 || nx >= pbm.width || ny < 0 || ny >= pbm.height || !pbm.data[ny][nx] {
+					c.Perimeter++
+				}
+			}
+		}
+	}
+	for i := range components {
+		components[i].CentroidX = float64(sumX[i]) / float64(components[i].Area)
+		components[i].CentroidY = float64(sumY[i]) / float64(components[i].Area)
+	}
+	return labels, components
+}
+
+
+// RemoveComponents sets to false the connected regions of true pixels for
+// which keep returns false. It returns the number of regions removed.
+func (pbm *PBM) RemoveComponents(keep func(Component) bool, conn Connectivity) int {
+	labels, components := pbm.Components(conn)
+	removed := make([]bool, len(components)+1)
+	count := 0
+	for _, c := range components {
+		if !keep(c) {
+			removed[c.Label] = true
+			count++
+		}
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if removed[labels[y][x]] {
+				pbm.data[y][x] = false
+			}
+		}
+	}
+	return count
+}
+
+
+// RemoveSmallComponents sets to false the connected regions of fewer than
+// minArea pixels, such as dust in a scan. It returns the number of regions
+// removed.
+func (pbm *PBM) RemoveSmallComponents(minArea int, conn Connectivity) int {
+	return pbm.RemoveComponents(func(c Component) bool {
+		return c.Area >= minArea
+	}, conn)
+}
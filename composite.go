@@ -0,0 +1,159 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// CompositeOperator is a Porter-Duff operator, telling which parts of the
+// source and destination are kept.
+type CompositeOperator int
+
+const (
+	OpOver    CompositeOperator = iota // source over destination
+	OpClear                            // nothing
+	OpSrc                              // source only
+	OpDst                              // destination only
+	OpDstOver                          // destination over source
+	OpIn                               // source where the destination is
+	OpDstIn                            // destination where the source is
+	OpOut                              // source where the destination is not
+	OpDstOut                           // destination where the source is not
+	OpAtop                             // source over destination, only where the destination is
+	OpDstAtop                          // destination over source, only where the source is
+	OpXor                              // source and destination where the other is not
+)
+
+
+// BlendMode tells how the colours of the source and the destination are mixed
+// where both are present.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendDifference
+)
+
+
+// CompositeOptions configures Composite.
+type CompositeOptions struct {
+	Operator CompositeOperator
+	Blend    BlendMode
+	Opacity  *float64 // multiplies the alpha of the source, from 0 (invisible) to 1; 1 when nil
+	DstAlpha *PGM     // alpha of the destination, updated with the result; opaque when nil
+}
+
+
+// factors returns the Porter-Duff weights of the source and the destination
+func (op CompositeOperator) factors(as, ad float64) (float64, float64) {
+	switch op {
+	case OpClear:
+		return 0, 0
+	case OpSrc:
+		return 1, 0
+	case OpDst:
+		return 0, 1
+	case OpDstOver:
+		return 1 - ad, 1
+	case OpIn:
+		return ad, 0
+	case OpDstIn:
+		return 0, as
+	case OpOut:
+		return 1 - ad, 0
+	case OpDstOut:
+		return 0, 1 - as
+	case OpAtop:
+		return ad, 1 - as
+	case OpDstAtop:
+		return 1 - ad, as
+	case OpXor:
+		return 1 - ad, 1 - as
+	}
+	return 1, 1 - as
+}
+
+
+// blend mixes a backdrop and a source value, both going from 0 to 1
+func (mode BlendMode) blend(cb, cs float64) float64 {
+	switch mode {
+	case BlendMultiply:
+		return cb * cs
+	case BlendScreen:
+		return cb + cs - cb*cs
+	case BlendOverlay:
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	case BlendDarken:
+		return math.Min(cb, cs)
+	case BlendLighten:
+		return math.Max(cb, cs)
+	case BlendDifference:
+		return math.Abs(cb - cs)
+	}
+	return cs
+}
+
+
+// Composite draws src onto dst with its top-left corner at the given point.
+// alpha is the transparency mask of src (maxval meaning opaque), which must
+// have its size; nil makes src opaque. Samples of src are rescaled to the
+// maxval of dst, and only the part inside the clip rectangle of dst is
+// changed. Without opts.DstAlpha the destination is opaque, and pixels an
+// operator leaves transparent become black.
+func Composite(dst, src *PPM, at Point, alpha *PGM, opts CompositeOptions) error {
+	if alpha != nil && (alpha.width != src.width || alpha.height != src.height) {
+		return errors.New("alpha mask does not have the size of the source")
+	}
+	if opts.DstAlpha != nil && (opts.DstAlpha.width != dst.width || opts.DstAlpha.height != dst.height) {
+		return errors.New("destination alpha does not have the size of the destination")
+	}
+	opacity := 1.0
+	if opts.Opacity != nil {
+		opacity = math.Min(math.Max(*opts.Opacity, 0), 1)
+	}
+
+	clip := dst.Clip()
+	for y := 0; y < src.height; y++ {
+		dy := at.Y + y
+		for x := 0; x < src.width; x++ {
+			dx := at.X + x
+			if !clip.contains(dx, dy) {
+				continue
+			}
+			as := opacity
+			if alpha != nil {
+				as *= float64(alpha.data[y][x]) / float64(alpha.max)
+			}
+			ad := 1.0
+			if opts.DstAlpha != nil {
+				ad = float64(opts.DstAlpha.data[dy][dx]) / float64(opts.DstAlpha.max)
+			}
+
+			fa, fb := opts.Operator.factors(as, ad)
+			ao := as*fa + ad*fb
+			sr, sg, sb := src.data[y][x].normalize(src.max)
+			br, bg, bb := dst.data[dy][dx].normalize(dst.max)
+			mix := func(cs, cb float64) float64 {
+				if ao == 0 {
+					return 0
+				}
+				// the source colour is blended with the backdrop where both are present
+				cs = (1-ad)*cs + ad*opts.Blend.blend(cb, cs)
+				return (as*fa*cs + ad*fb*cb) / ao
+			}
+			dst.data[dy][dx] = pixelFrom(mix(sr, br), mix(sg, bg), mix(sb, bb), dst.max)
+			if opts.DstAlpha != nil {
+				opts.DstAlpha.data[dy][dx] = sample(ao, opts.DstAlpha.max)
+			}
+		}
+	}
+	return nil
+}
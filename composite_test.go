@@ -0,0 +1,48 @@
+package Netpbm
+
+import "testing"
+
+func TestCompositeOpacity(t *testing.T) {
+	white := NewPPM(1, 1, 255)
+	white.fill(Pixel{255, 255, 255})
+	opacity := func(v float64) *float64 {
+		return &v
+	}
+	for _, c := range []struct {
+		opacity *float64
+		want    uint8
+	}{
+		{nil, 255},
+		{opacity(0), 0},
+		{opacity(0.5), 128},
+		{opacity(1), 255},
+	} {
+		dst := NewPPM(1, 1, 255)
+		if err := Composite(dst, white, Point{}, nil, CompositeOptions{Opacity: c.opacity}); err != nil {
+			t.Fatal(err)
+		}
+		if got := dst.data[0][0]; got != (Pixel{c.want, c.want, c.want}) {
+			t.Errorf("want %d, got %v", c.want, got)
+		}
+	}
+}
+
+
+func TestCompositeClip(t *testing.T) {
+	white := NewPPM(4, 4, 255)
+	white.fill(Pixel{255, 255, 255})
+	dst := NewPPM(6, 6, 255)
+	alpha := NewPGM(6, 6, 255)
+	dst.SetClip(Rectangle{2, 2, 2, 3})
+	if err := Composite(dst, white, Point{1, 1}, nil, CompositeOptions{DstAlpha: alpha}); err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			in := x >= 2 && x < 4 && y >= 2 && y < 5
+			if (dst.data[y][x] == Pixel{255, 255, 255}) != in || (alpha.data[y][x] == 255) != in {
+				t.Errorf("(%d, %d) is wrong", x, y)
+			}
+		}
+	}
+}
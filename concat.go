@@ -0,0 +1,155 @@
+package Netpbm
+
+// Direction tells how images are joined together.
+type Direction int
+
+const (
+	LeftRight Direction = iota
+	TopBottom
+)
+
+
+// Alignment places an image along the axis perpendicular to the concatenation,
+// or inside a montage cell.
+type Alignment int
+
+const (
+	AlignStart Alignment = iota // top or left
+	AlignCenter
+	AlignEnd // bottom or right
+)
+
+
+// offset returns where a segment of length size starts inside a span of length total
+func (a Alignment) offset(size, total int) int {
+	switch a {
+	case AlignCenter:
+		return (total - size) / 2
+	case AlignEnd:
+		return total - size
+	}
+	return 0
+}
+
+
+// concatLayout computes the size of the joined image and the top-left corner of
+// every part, given their sizes.
+func concatLayout(dir Direction, align Alignment, sizes [][2]int) (int, int, []Point) {
+	width, height := 0, 0
+	for _, s := range sizes {
+		if dir == LeftRight {
+			width += s[0]
+			if s[1] > height {
+				height = s[1]
+			}
+		} else {
+			height += s[1]
+			if s[0] > width {
+				width = s[0]
+			}
+		}
+	}
+
+	at := make([]Point, len(sizes))
+	pos := 0
+	for i, s := range sizes {
+		if dir == LeftRight {
+			at[i] = Point{pos, align.offset(s[1], height)}
+			pos += s[0]
+		} else {
+			at[i] = Point{align.offset(s[0], width), pos}
+			pos += s[1]
+		}
+	}
+	return width, height, at
+}
+
+
+func imageSizes[T Image](images []T) [][2]int {
+	sizes := make([][2]int, len(images))
+	for i, img := range images {
+		sizes[i][0], sizes[i][1] = img.Size()
+	}
+	return sizes
+}
+
+
+// ConcatPBM joins bitmaps left to right or top to bottom, like pnmcat.
+// The space left by smaller images is set to fill.
+func ConcatPBM(dir Direction, align Alignment, fill bool, images ...*PBM) *PBM {
+	width, height, at := concatLayout(dir, align, imageSizes(images))
+	pbm := NewPBM(width, height)
+	pbm.fill(fill)
+	for i, img := range images {
+		pbm.paste(img, at[i])
+	}
+	return pbm
+}
+
+
+// ConcatPGM joins graymaps left to right or top to bottom, like pnmcat.
+// The result takes the largest maxval, the other images are rescaled to it,
+// and the space left by smaller images is set to fill.
+func ConcatPGM(dir Direction, align Alignment, fill uint8, images ...*PGM) *PGM {
+	width, height, at := concatLayout(dir, align, imageSizes(images))
+	max := 0
+	for _, img := range images {
+		if img.max > max {
+			max = img.max
+		}
+	}
+	pgm := NewPGM(width, height, max)
+	pgm.fill(fill)
+	for i, img := range images {
+		if img.max != max {
+			scaled := NewPGM(img.width, img.height, max)
+			for y := 0; y < img.height; y++ {
+				for x := 0; x < img.width; x++ {
+					scaled.data[y][x] = rescale(img.data[y][x], img.max, max)
+				}
+			}
+			img = scaled
+		}
+		pgm.paste(img, at[i])
+	}
+	return pgm
+}
+
+
+// ConcatPPM joins pixmaps left to right or top to bottom, like pnmcat.
+// The result takes the largest maxval, the other images are rescaled to it,
+// and the space left by smaller images is set to fill.
+func ConcatPPM(dir Direction, align Alignment, fill Pixel, images ...*PPM) *PPM {
+	width, height, at := concatLayout(dir, align, imageSizes(images))
+	max := 0
+	for _, img := range images {
+		if img.max > max {
+			max = img.max
+		}
+	}
+	ppm := NewPPM(width, height, max)
+	ppm.fill(fill)
+	for i, img := range images {
+		if img.max != max {
+			img = asPPM(img, max)
+		}
+		ppm.paste(img, at[i])
+	}
+	return ppm
+}
+
+
+// Concat joins images of any type. They are promoted to the richest format
+// present (PBM < PGM < PPM) and to the largest maxval. fill is an 8-bit colour,
+// scaled to the result maxval and reduced to gray or black and white as needed.
+func Concat(dir Direction, align Alignment, fill Pixel, images ...Image) (Image, error) {
+	f, max, err := commonFormat(images)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*PPM, len(images))
+	for i, img := range images {
+		parts[i] = asPPM(img, max)
+	}
+	return fromPPM(ConcatPPM(dir, align, colorFor(fill, f, max), parts...), f), nil
+}
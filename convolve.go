@@ -0,0 +1,387 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// BorderMode tells which values a filter reads past the edges of the image.
+type BorderMode int
+
+const (
+	BorderClamp  BorderMode = iota // the edge pixel is repeated
+	BorderMirror                   // the image is reflected, without repeating the edge pixel
+	BorderWrap                     // the image repeats, the right edge touching the left one
+	BorderZero                     // black
+)
+
+
+// ConvolveOptions configures Convolve, like the options of netpbm's pnmconvol.
+type ConvolveOptions struct {
+	Normalize bool    // divide the kernel by the sum of its weights, unless it is 0
+	Bias      float64 // added to every result, in samples
+	Border    BorderMode
+}
+
+
+// borderIndex brings index i back inside [0, n). It returns false when the
+// value must be taken as 0.
+func borderIndex(i, n int, mode BorderMode) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch mode {
+	case BorderZero:
+		return 0, false
+	case BorderWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i, true
+	case BorderMirror:
+		if n == 1 {
+			return 0, true
+		}
+		period := 2 * (n - 1)
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - i
+		}
+		return i, true
+	}
+	if i < 0 {
+		return 0, true
+	}
+	return n - 1, true
+}
+
+
+// convolvePlane applies a 2D kernel, centred on its middle element, to a plane
+func convolvePlane(plane [][]float64, kernel [][]float64, border BorderMode) [][]float64 {
+	height := len(plane)
+	width := 0
+	if height > 0 {
+		width = len(plane[0])
+	}
+	kh, kw := len(kernel), len(kernel[0])
+	cy, cx := kh/2, kw/2
+	out := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for j := 0; j < kh; j++ {
+				sy, ok := borderIndex(y+j-cy, height, border)
+				if !ok {
+					continue
+				}
+				for i := 0; i < kw; i++ {
+					if kernel[j][i] == 0 {
+						continue
+					}
+					sx, ok := borderIndex(x+i-cx, width, border)
+					if ok {
+						sum += kernel[j][i] * plane[sy][sx]
+					}
+				}
+			}
+			out[y][x] = sum
+		}
+	}
+	return out
+}
+
+
+// convolveSeparable applies the kernel column x row, row being applied along
+// the lines and column along the columns
+func convolveSeparable(plane [][]float64, row, column []float64, border BorderMode) [][]float64 {
+	height := len(plane)
+	width := 0
+	if height > 0 {
+		width = len(plane[0])
+	}
+	cx, cy := len(row)/2, len(column)/2
+	tmp := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		tmp[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for i, k := range row {
+				if sx, ok := borderIndex(x+i-cx, width, border); ok {
+					sum += k * plane[y][sx]
+				}
+			}
+			tmp[y][x] = sum
+		}
+	}
+	out := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for j, k := range column {
+				if sy, ok := borderIndex(y+j-cy, height, border); ok {
+					sum += k * tmp[sy][x]
+				}
+			}
+			out[y][x] = sum
+		}
+	}
+	return out
+}
+
+
+// separate splits a kernel into a column and a row whose product gives it
+// back, when it has rank one
+func separate(kernel [][]float64) ([]float64, []float64, bool) {
+	// pivot on the largest weight
+	py, px, best := 0, 0, 0.0
+	for y, r := range kernel {
+		for x, v := range r {
+			if math.Abs(v) > best {
+				py, px, best = y, x, math.Abs(v)
+			}
+		}
+	}
+	if best == 0 {
+		return nil, nil, false
+	}
+	row := append([]float64(nil), kernel[py]...)
+	column := make([]float64, len(kernel))
+	for y := range kernel {
+		column[y] = kernel[y][px] / kernel[py][px]
+	}
+	for y, r := range kernel {
+		for x, v := range r {
+			if math.Abs(column[y]*row[x]-v) > 1e-9*best {
+				return nil, nil, false
+			}
+		}
+	}
+	return column, row, true
+}
+
+
+// prepareKernel checks the kernel and applies the normalisation option
+func prepareKernel(kernel [][]float64, opts ConvolveOptions) ([][]float64, error) {
+	if len(kernel) == 0 || len(kernel[0]) == 0 {
+		return nil, errors.New("empty kernel")
+	}
+	sum := 0.0
+	for _, r := range kernel {
+		if len(r) != len(kernel[0]) {
+			return nil, errors.New("kernel rows do not have the same length")
+		}
+		for _, v := range r {
+			sum += v
+		}
+	}
+	if !opts.Normalize || sum == 0 || sum == 1 {
+		return kernel, nil
+	}
+	normalized := make([][]float64, len(kernel))
+	for y, r := range kernel {
+		normalized[y] = make([]float64, len(r))
+		for x, v := range r {
+			normalized[y][x] = v / sum
+		}
+	}
+	return normalized, nil
+}
+
+
+// filterPlane convolves a plane, going through two 1D passes when the kernel
+// is separable
+func filterPlane(plane [][]float64, kernel [][]float64, border BorderMode) [][]float64 {
+	if column, row, ok := separate(kernel); ok && len(kernel) > 1 && len(kernel[0]) > 1 {
+		return convolveSeparable(plane, row, column, border)
+	}
+	return convolvePlane(plane, kernel, border)
+}
+
+
+// gaussianKernel returns the normalised 1D Gaussian of the given sigma, cut at
+// radius
+func gaussianKernel(sigma float64, radius int) []float64 {
+	kernel := make([]float64, 2*radius+1)
+	total := 0.0
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+	return kernel
+}
+
+
+// plane returns the samples of the graymap as floats
+func (pgm *PGM) plane() [][]float64 {
+	plane := make([][]float64, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		plane[y] = make([]float64, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			plane[y][x] = float64(pgm.data[y][x])
+		}
+	}
+	return plane
+}
+
+
+// setPlane stores float samples in the graymap, rounded and clamped
+func (pgm *PGM) setPlane(plane [][]float64) {
+	m := float64(pgm.max)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = uint8(math.Round(math.Min(math.Max(plane[y][x], 0), m)))
+		}
+	}
+}
+
+
+// planes returns the red, green and blue samples of the pixmap as floats
+func (ppm *PPM) planes() [3][][]float64 {
+	var planes [3][][]float64
+	for c := range planes {
+		planes[c] = make([][]float64, ppm.height)
+		for y := 0; y < ppm.height; y++ {
+			planes[c][y] = make([]float64, ppm.width)
+			for x := 0; x < ppm.width; x++ {
+				planes[c][y][x] = float64(ppm.data[y][x].channel(Channel(c)))
+			}
+		}
+	}
+	return planes
+}
+
+
+// setPlanes stores float samples in the pixmap, rounded and clamped
+func (ppm *PPM) setPlanes(planes [3][][]float64) {
+	m := float64(ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			for c := range planes {
+				ppm.data[y][x].setChannel(Channel(c), uint8(math.Round(math.Min(math.Max(planes[c][y][x], 0), m))))
+			}
+		}
+	}
+}
+
+
+// mapPlane replaces the samples of the graymap by f applied to them as floats
+func (pgm *PGM) mapPlane(f func([][]float64) [][]float64) {
+	pgm.setPlane(f(pgm.plane()))
+}
+
+
+// mapPlanes replaces every channel of the pixmap by f applied to it as floats
+func (ppm *PPM) mapPlanes(f func([][]float64) [][]float64) {
+	planes := ppm.planes()
+	for c := range planes {
+		planes[c] = f(planes[c])
+	}
+	ppm.setPlanes(planes)
+}
+
+
+// addBias adds bias to every value of a plane
+func addBias(plane [][]float64, bias float64) [][]float64 {
+	if bias != 0 {
+		for _, r := range plane {
+			for x := range r {
+				r[x] += bias
+			}
+		}
+	}
+	return plane
+}
+
+
+// Convolve filters the graymap with kernel, a grid of weights centred on its
+// middle element, like pnmconvol. Results are rounded and clamped to
+// [0, maxval]. Kernels that are the product of a column and a row, such as
+// box and Gaussian ones, are applied in two faster 1D passes.
+func (pgm *PGM) Convolve(kernel [][]float64, opts ConvolveOptions) error {
+	kernel, err := prepareKernel(kernel, opts)
+	if err != nil {
+		return err
+	}
+	pgm.setPlane(addBias(filterPlane(pgm.plane(), kernel, opts.Border), opts.Bias))
+	return nil
+}
+
+
+// Convolve filters every channel of the pixmap with kernel. See PGM.Convolve.
+func (ppm *PPM) Convolve(kernel [][]float64, opts ConvolveOptions) error {
+	kernel, err := prepareKernel(kernel, opts)
+	if err != nil {
+		return err
+	}
+	planes := ppm.planes()
+	for c := range planes {
+		planes[c] = addBias(filterPlane(planes[c], kernel, opts.Border), opts.Bias)
+	}
+	ppm.setPlanes(planes)
+	return nil
+}
+
+
+// separableKernel checks the two halves of a separable kernel and applies the
+// normalisation option to them
+func separableKernel(row, column []float64, opts ConvolveOptions) ([]float64, []float64, error) {
+	if len(row) == 0 || len(column) == 0 {
+		return nil, nil, errors.New("empty kernel")
+	}
+	if !opts.Normalize {
+		return row, column, nil
+	}
+	scale := func(k []float64) []float64 {
+		sum := 0.0
+		for _, v := range k {
+			sum += v
+		}
+		if sum == 0 {
+			return k
+		}
+		out := make([]float64, len(k))
+		for i, v := range k {
+			out[i] = v / sum
+		}
+		return out
+	}
+	return scale(row), scale(column), nil
+}
+
+
+// ConvolveSeparable filters the graymap with the kernel column x row, applied
+// as a horizontal pass with row then a vertical pass with column.
+func (pgm *PGM) ConvolveSeparable(row, column []float64, opts ConvolveOptions) error {
+	row, column, err := separableKernel(row, column, opts)
+	if err != nil {
+		return err
+	}
+	pgm.setPlane(addBias(convolveSeparable(pgm.plane(), row, column, opts.Border), opts.Bias))
+	return nil
+}
+
+
+// ConvolveSeparable filters every channel of the pixmap with the kernel
+// column x row. See PGM.ConvolveSeparable.
+func (ppm *PPM) ConvolveSeparable(row, column []float64, opts ConvolveOptions) error {
+	row, column, err := separableKernel(row, column, opts)
+	if err != nil {
+		return err
+	}
+	planes := ppm.planes()
+	for c := range planes {
+		planes[c] = addBias(convolveSeparable(planes[c], row, column, opts.Border), opts.Bias)
+	}
+	ppm.setPlanes(planes)
+	return nil
+}
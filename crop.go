@@ -0,0 +1,137 @@
+package Netpbm
+
+// cropBounds returns the smallest area holding every pixel that is not
+// background. When the whole image is background, the full image is returned.
+func cropBounds(width, height int, isBackground func(x, y int) bool) Rectangle {
+	left, right, top, bottom := width, -1, height, -1
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if isBackground(x, y) {
+				continue
+			}
+			if x < left {
+				left = x
+			}
+			if x > right {
+				right = x
+			}
+			if y < top {
+				top = y
+			}
+			if y > bottom {
+				bottom = y
+			}
+		}
+	}
+	if right < 0 {
+		return Rectangle{0, 0, width, height}
+	}
+	return Rectangle{left, top, right - left + 1, bottom - top + 1}
+}
+
+
+// cornerIndex returns which of the four corners holds the background colour:
+// the one shared by most corners, the top-left one on a tie.
+func cornerIndex(same func(i, j int) bool) int {
+	best, bestCount := 0, 0
+	for i := 0; i < 4; i++ {
+		count := 0
+		for j := 0; j < 4; j++ {
+			if same(i, j) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+
+// corners returns the coordinates of the four corners of an image
+func corners(width, height int) [4]Point {
+	return [4]Point{{0, 0}, {width - 1, 0}, {0, height - 1}, {width - 1, height - 1}}
+}
+
+
+// AutoCrop removes the borders having the colour of the corners, like pnmcrop.
+// It returns the kept area, in the coordinates of the image before cropping.
+func (pbm *PBM) AutoCrop() Rectangle {
+	if pbm.width == 0 || pbm.height == 0 {
+		return Rectangle{}
+	}
+	c := corners(pbm.width, pbm.height)
+	bg := c[cornerIndex(func(i, j int) bool {
+		return pbm.data[c[i].Y][c[i].X] == pbm.data[c[j].Y][c[j].X]
+	})]
+	background := pbm.data[bg.Y][bg.X]
+
+	r := cropBounds(pbm.width, pbm.height, func(x, y int) bool {
+		return pbm.data[y][x] == background
+	})
+	magicNumber := pbm.magicNumber
+	*pbm = *pbm.SubImage(r)
+	pbm.magicNumber = magicNumber
+	return r
+}
+
+
+// AutoCrop removes the borders having the colour of the corners, like pnmcrop.
+// Pixels differing from that colour by at most tolerance are considered part
+// of the border. It returns the kept area, in the coordinates of the image
+// before cropping.
+func (pgm *PGM) AutoCrop(tolerance int) Rectangle {
+	if pgm.width == 0 || pgm.height == 0 {
+		return Rectangle{}
+	}
+	near := func(a, b uint8) bool {
+		d := int(a) - int(b)
+		return d <= tolerance && -d <= tolerance
+	}
+	c := corners(pgm.width, pgm.height)
+	bg := c[cornerIndex(func(i, j int) bool {
+		return near(pgm.data[c[i].Y][c[i].X], pgm.data[c[j].Y][c[j].X])
+	})]
+	background := pgm.data[bg.Y][bg.X]
+
+	r := cropBounds(pgm.width, pgm.height, func(x, y int) bool {
+		return near(pgm.data[y][x], background)
+	})
+	magicNumber := pgm.magicNumber
+	*pgm = *pgm.SubImage(r)
+	pgm.magicNumber = magicNumber
+	return r
+}
+
+
+// AutoCrop removes the borders having the colour of the corners, like pnmcrop.
+// Pixels whose channels all differ from that colour by at most tolerance are
+// considered part of the border. It returns the kept area, in the coordinates
+// of the image before cropping.
+func (ppm *PPM) AutoCrop(tolerance int) Rectangle {
+	if ppm.width == 0 || ppm.height == 0 {
+		return Rectangle{}
+	}
+	near := func(a, b Pixel) bool {
+		for _, d := range [3]int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+			if d > tolerance || -d > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	c := corners(ppm.width, ppm.height)
+	bg := c[cornerIndex(func(i, j int) bool {
+		return near(ppm.data[c[i].Y][c[i].X], ppm.data[c[j].Y][c[j].X])
+	})]
+	background := ppm.data[bg.Y][bg.X]
+
+	r := cropBounds(ppm.width, ppm.height, func(x, y int) bool {
+		return near(ppm.data[y][x], background)
+	})
+	magicNumber := ppm.magicNumber
+	*ppm = *ppm.SubImage(r)
+	ppm.magicNumber = magicNumber
+	return r
+}
@@ -0,0 +1,274 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// The denoisers work on a list of channels, one for a graymap and three for a
+// pixmap, so that colour pixels are compared as a whole.
+
+// medianPlane replaces every value by the median of the square of side
+// 2*radius+1 around it, the edges being repeated
+func medianPlane(plane [][]float64, radius int) [][]float64 {
+	height := len(plane)
+	width := 0
+	if height > 0 {
+		width = len(plane[0])
+	}
+	out := make([][]float64, height)
+	window := make([]float64, 0, (2*radius+1)*(2*radius+1))
+	for y := 0; y < height; y++ {
+		out[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			window = window[:0]
+			for j := -radius; j <= radius; j++ {
+				sy, _ := borderIndex(y+j, height, BorderClamp)
+				for i := -radius; i <= radius; i++ {
+					sx, _ := borderIndex(x+i, width, BorderClamp)
+					window = append(window, plane[sy][sx])
+				}
+			}
+			sort.Float64s(window)
+			out[y][x] = window[len(window)/2]
+		}
+	}
+	return out
+}
+
+
+// bilateral averages every pixel with its neighbours, weighted both by their
+// distance (sigmaSpace, in pixels) and by how much their value differs
+// (sigmaRange, in samples), which smooths flat areas but keeps the edges
+func bilateral(channels [][][]float64, sigmaSpace, sigmaRange float64) [][][]float64 {
+	height := len(channels[0])
+	width := 0
+	if height > 0 {
+		width = len(channels[0][0])
+	}
+	radius := int(math.Ceil(2 * sigmaSpace))
+	out := make([][][]float64, len(channels))
+	for c := range out {
+		out[c] = make([][]float64, height)
+		for y := range out[c] {
+			out[c][y] = make([]float64, width)
+		}
+	}
+	sums := make([]float64, len(channels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			total := 0.0
+			for c := range sums {
+				sums[c] = 0
+			}
+			for j := -radius; j <= radius; j++ {
+				sy := y + j
+				if sy < 0 || sy >= height {
+					continue
+				}
+				for i := -radius; i <= radius; i++ {
+					sx := x + i
+					if sx < 0 || sx >= width {
+						continue
+					}
+					d := 0.0
+					for _, ch := range channels {
+						diff := ch[sy][sx] - ch[y][x]
+						d += diff * diff
+					}
+					w := math.Exp(-float64(i*i+j*j)/(2*sigmaSpace*sigmaSpace) - d/(2*sigmaRange*sigmaRange))
+					for c, ch := range channels {
+						sums[c] += w * ch[sy][sx]
+					}
+					total += w
+				}
+			}
+			for c := range channels {
+				out[c][y][x] = sums[c] / total
+			}
+		}
+	}
+	return out
+}
+
+
+// nonLocalMeans averages every pixel with the pixels of the search window
+// whose surrounding patch looks like its own, h (in samples) telling how
+// different two patches may be
+func nonLocalMeans(channels [][][]float64, h float64, patchRadius, searchRadius int) [][][]float64 {
+	height := len(channels[0])
+	width := 0
+	if height > 0 {
+		width = len(channels[0][0])
+	}
+	out := make([][][]float64, len(channels))
+	for c := range out {
+		out[c] = make([][]float64, height)
+		for y := range out[c] {
+			out[c][y] = make([]float64, width)
+		}
+	}
+	value := func(ch [][]float64, x, y int) float64 {
+		x, _ = borderIndex(x, width, BorderMirror)
+		y, _ = borderIndex(y, height, BorderMirror)
+		return ch[y][x]
+	}
+	patchSize := float64((2*patchRadius + 1) * (2*patchRadius + 1) * len(channels))
+	sums := make([]float64, len(channels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			total := 0.0
+			for c := range sums {
+				sums[c] = 0
+			}
+			for j := -searchRadius; j <= searchRadius; j++ {
+				sy := y + j
+				if sy < 0 || sy >= height {
+					continue
+				}
+				for i := -searchRadius; i <= searchRadius; i++ {
+					sx := x + i
+					if sx < 0 || sx >= width {
+						continue
+					}
+					// mean squared difference between the two patches
+					d := 0.0
+					for _, ch := range channels {
+						for pj := -patchRadius; pj <= patchRadius; pj++ {
+							for pi := -patchRadius; pi <= patchRadius; pi++ {
+								diff := value(ch, x+pi, y+pj) - value(ch, sx+pi, sy+pj)
+								d += diff * diff
+							}
+						}
+					}
+					w := math.Exp(-d / patchSize / (h * h))
+					for c, ch := range channels {
+						sums[c] += w * ch[sy][sx]
+					}
+					total += w
+				}
+			}
+			for c := range channels {
+				out[c][y][x] = sums[c] / total
+			}
+		}
+	}
+	return out
+}
+
+
+// MedianFilter replaces every pixel by the median of the square of side
+// 2*radius+1 around it, which removes isolated specks without blurring edges.
+// A radius of 0 or less leaves the image as it is.
+func (pgm *PGM) MedianFilter(radius int) {
+	if radius <= 0 {
+		return
+	}
+	pgm.mapPlane(func(plane [][]float64) [][]float64 {
+		return medianPlane(plane, radius)
+	})
+}
+
+
+// BilateralFilter smooths the graymap while keeping its edges. sigmaSpace is
+// the reach of the filter in pixels, sigmaRange the difference in samples
+// above which neighbours hardly count.
+func (pgm *PGM) BilateralFilter(sigmaSpace, sigmaRange float64) {
+	if sigmaSpace <= 0 || sigmaRange <= 0 {
+		return
+	}
+	pgm.setPlane(bilateral([][][]float64{pgm.plane()}, sigmaSpace, sigmaRange)[0])
+}
+
+
+// NonLocalMeans denoises the graymap by averaging pixels whose neighbourhoods
+// (patches of side 2*patchRadius+1) look alike, searched in the square of side
+// 2*searchRadius+1. h is the filtering strength, in samples, about the
+// standard deviation of the noise. Radii of 0 select 1 and 5.
+func (pgm *PGM) NonLocalMeans(h float64, patchRadius, searchRadius int) {
+	if h <= 0 {
+		return
+	}
+	if patchRadius <= 0 {
+		patchRadius = 1
+	}
+	if searchRadius <= 0 {
+		searchRadius = 5
+	}
+	pgm.setPlane(nonLocalMeans([][][]float64{pgm.plane()}, h, patchRadius, searchRadius)[0])
+}
+
+
+// MedianFilter replaces every sample by the median of the square of side
+// 2*radius+1 around it, channel by channel. A radius of 0 or less leaves the
+// image as it is.
+func (ppm *PPM) MedianFilter(radius int) {
+	if radius <= 0 {
+		return
+	}
+	ppm.mapPlanes(func(plane [][]float64) [][]float64 {
+		return medianPlane(plane, radius)
+	})
+}
+
+
+// BilateralFilter smooths the pixmap while keeping its edges, the difference
+// between two pixels being their distance in RGB. See PGM.BilateralFilter.
+func (ppm *PPM) BilateralFilter(sigmaSpace, sigmaRange float64) {
+	if sigmaSpace <= 0 || sigmaRange <= 0 {
+		return
+	}
+	planes := ppm.planes()
+	out := bilateral(planes[:], sigmaSpace, sigmaRange)
+	ppm.setPlanes([3][][]float64{out[0], out[1], out[2]})
+}
+
+
+// NonLocalMeans denoises the pixmap, patches being compared on all three
+// channels. See PGM.NonLocalMeans.
+func (ppm *PPM) NonLocalMeans(h float64, patchRadius, searchRadius int) {
+	if h <= 0 {
+		return
+	}
+	if patchRadius <= 0 {
+		patchRadius = 1
+	}
+	if searchRadius <= 0 {
+		searchRadius = 5
+	}
+	planes := ppm.planes()
+	out := nonLocalMeans(planes[:], h, patchRadius, searchRadius)
+	ppm.setPlanes([3][][]float64{out[0], out[1], out[2]})
+}
+
+
+// Despeckle removes salt-and-pepper noise from the bitmap, like pbmclean: every
+// pixel having fewer than minNeighbors of its 8 neighbours of its own value is
+// flipped. minNeighbors is 1 when 0, which only removes isolated pixels.
+// It returns the number of pixels flipped.
+func (pbm *PBM) Despeckle(minNeighbors int) int {
+	if minNeighbors <= 0 {
+		minNeighbors = 1
+	}
+	flip := make([]Point, 0)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			same := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if (dx != 0 || dy != 0) && nx >= 0 && nx < pbm.width && ny >= 0 && ny < pbm.height && pbm.data[ny][nx] == pbm.data[y][x] {
+						same++
+					}
+				}
+			}
+			if same < minNeighbors {
+				flip = append(flip, Point{x, y})
+			}
+		}
+	}
+	for _, p := range flip {
+		pbm.data[p.Y][p.X] = !pbm.data[p.Y][p.X]
+	}
+	return len(flip)
+}
@@ -0,0 +1,27 @@
+package Netpbm
+
+import "testing"
+
+func TestMedianFilterRadius(t *testing.T) {
+	for _, radius := range []int{-2, 0} {
+		pgm := NewPGM(5, 5, 255)
+		pgm.data[2][2] = 200
+		pgm.MedianFilter(radius)
+		if pgm.data[2][2] != 200 {
+			t.Errorf("radius %d: the graymap changed", radius)
+		}
+		ppm := NewPPM(5, 5, 255)
+		ppm.data[2][2] = Pixel{200, 100, 50}
+		ppm.MedianFilter(radius)
+		if ppm.data[2][2] != (Pixel{200, 100, 50}) {
+			t.Errorf("radius %d: the pixmap changed", radius)
+		}
+	}
+	// a speck is removed from radius 1
+	pgm := NewPGM(5, 5, 255)
+	pgm.data[2][2] = 200
+	pgm.MedianFilter(1)
+	if pgm.data[2][2] != 0 {
+		t.Errorf("got %d, want the speck removed", pgm.data[2][2])
+	}
+}
@@ -0,0 +1,155 @@
+package Netpbm
+
+// DitherMethod tells how ToPBM turns gray levels into black and white.
+type DitherMethod int
+
+const (
+	DitherThreshold      DitherMethod = iota // fixed threshold at half the maxval
+	DitherFloydSteinberg                     // error diffusion
+	DitherAtkinson                           // error diffusion, only 3/4 of the error is kept
+	DitherJarvisJudiceNinke                  // error diffusion
+	DitherStucki                             // error diffusion
+	DitherSierra                             // error diffusion
+	DitherBayer                              // ordered dithering, see DitherOptions.BayerSize
+)
+
+
+// DitherOptions configures ToPBM, like netpbm's pamditherbw. The zero value
+// gives the plain threshold.
+type DitherOptions struct {
+	Method     DitherMethod
+	Serpentine bool        // error diffusion goes right to left on odd rows
+	BayerSize  int         // side of the Bayer matrix, a power of two, 4 when 0
+	Gray       GrayOptions // conversion used by PPM.ToPBM before dithering
+}
+
+
+// diffusion is one neighbour receiving a part of the quantisation error
+type diffusion struct {
+	dx, dy, weight int
+}
+
+
+// diffusionKernels gives, for each error diffusion method, the neighbours and
+// the divisor of their weights
+var diffusionKernels = map[DitherMethod]struct {
+	spread  []diffusion
+	divisor int
+}{
+	DitherFloydSteinberg: {[]diffusion{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}, 16},
+	DitherAtkinson: {[]diffusion{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}, 8},
+	DitherJarvisJudiceNinke: {[]diffusion{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}, 48},
+	DitherStucki: {[]diffusion{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}, 42},
+	DitherSierra: {[]diffusion{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}, 32},
+}
+
+
+// bayerMatrix returns the n*n Bayer index matrix, n being a power of two
+func bayerMatrix(n int) [][]int {
+	m := [][]int{{0}}
+	for size := 1; size < n; size *= 2 {
+		next := make([][]int, size*2)
+		for y := range next {
+			next[y] = make([]int, size*2)
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := 4 * m[y][x]
+				next[y][x] = v
+				next[y][x+size] = v + 2
+				next[y+size][x] = v + 3
+				next[y+size][x+size] = v + 1
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+
+// dither converts gray levels going from 0 to 1 into a bitmap, true pixels
+// being the bright ones as with the plain threshold.
+func dither(levels [][]float64, opts DitherOptions) [][]bool {
+	height := len(levels)
+	width := 0
+	if height > 0 {
+		width = len(levels[0])
+	}
+	out := make([][]bool, height)
+	for y := range out {
+		out[y] = make([]bool, width)
+	}
+
+	if opts.Method == DitherBayer {
+		n := 4
+		if opts.BayerSize > 0 {
+			n = 1
+			for n < opts.BayerSize {
+				n *= 2
+			}
+		}
+		m := bayerMatrix(n)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				out[y][x] = levels[y][x] > (float64(m[y%n][x%n])+0.5)/float64(n*n)
+			}
+		}
+		return out
+	}
+
+	kernel, diffuse := diffusionKernels[opts.Method]
+	if !diffuse {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				out[y][x] = levels[y][x] > 0.5
+			}
+		}
+		return out
+	}
+
+	// work on a copy, the errors are added to it
+	values := make([][]float64, height)
+	for y := range values {
+		values[y] = append([]float64(nil), levels[y]...)
+	}
+	for y := 0; y < height; y++ {
+		reverse := opts.Serpentine && y%2 == 1
+		for i := 0; i < width; i++ {
+			x, dir := i, 1
+			if reverse {
+				x, dir = width-1-i, -1
+			}
+			v := values[y][x]
+			out[y][x] = v > 0.5
+			if out[y][x] {
+				v -= 1
+			}
+			for _, d := range kernel.spread {
+				nx, ny := x+d.dx*dir, y+d.dy
+				if nx >= 0 && nx < width && ny < height {
+					values[ny][nx] += v * float64(d.weight) / float64(kernel.divisor)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// DominantColor is one of the main colours of an image.
+type DominantColor struct {
+	Color    Pixel
+	Coverage float64 // percentage of the counted pixels close to Color
+}
+
+
+// DominantOptions configures DominantColors.
+type DominantOptions struct {
+	IgnoreWhite bool    // skip the pixels close to white, such as backgrounds
+	IgnoreBlack bool    // skip the pixels close to black
+	Tolerance   float64 // CIELAB distance to white or black of the skipped pixels, 10 when 0
+	Iterations  int     // k-means iterations, 10 when 0
+}
+
+
+// mergeDistance is the CIELAB distance under which two dominant colours are
+// reported as one
+const mergeDistance = 5
+
+
+// DominantColors returns at most n main colours of the image, the most present
+// first. Colours are grouped with k-means in CIELAB, so that the groups match
+// what the eye sees as similar colours.
+func (ppm *PPM) DominantColors(n int, opts DominantOptions) []DominantColor {
+	tolerance := opts.Tolerance
+	if tolerance <= 0 {
+		tolerance = 10
+	}
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = 10
+	}
+	white, black := Lab{100, 0, 0}, Lab{0, 0, 0}
+
+	colors := make([]colorCount, 0)
+	labs := make([]Lab, 0)
+	total := 0
+	for _, c := range ppm.colors() {
+		lab := c.color.ToLab(ppm.max)
+		if opts.IgnoreWhite && lab.DeltaE(white) <= tolerance {
+			continue
+		}
+		if opts.IgnoreBlack && lab.DeltaE(black) <= tolerance {
+			continue
+		}
+		colors = append(colors, c)
+		labs = append(labs, lab)
+		total += c.count
+	}
+	if n <= 0 || total == 0 {
+		return nil
+	}
+
+	// start from the median cut palette, then refine in CIELAB
+	centres := make([]Lab, 0, n)
+	for _, p := range medianCut(append([]colorCount(nil), colors...), n) {
+		centres = append(centres, p.ToLab(ppm.max))
+	}
+	assigned := make([]int, len(colors))
+	for it := 0; it < iterations; it++ {
+		for i, lab := range labs {
+			best, bestDistance := 0, math.Inf(1)
+			for k, c := range centres {
+				if d := lab.DeltaE(c); d < bestDistance {
+					best, bestDistance = k, d
+				}
+			}
+			assigned[i] = best
+		}
+		sums := make([]Lab, len(centres))
+		weights := make([]float64, len(centres))
+		for i, lab := range labs {
+			w := float64(colors[i].count)
+			k := assigned[i]
+			sums[k].L += w * lab.L
+			sums[k].A += w * lab.A
+			sums[k].B += w * lab.B
+			weights[k] += w
+		}
+		moved := false
+		for k := range centres {
+			if weights[k] == 0 {
+				continue
+			}
+			c := Lab{sums[k].L / weights[k], sums[k].A / weights[k], sums[k].B / weights[k]}
+			if c.DeltaE(centres[k]) > 0.01 {
+				moved = true
+			}
+			centres[k] = c
+		}
+		if !moved {
+			break
+		}
+	}
+
+	counts := make([]int, len(centres))
+	for i, k := range assigned {
+		counts[k] += colors[i].count
+	}
+	// groups the eye can hardly tell apart are merged
+	for a := range centres {
+		for b := a + 1; b < len(centres); b++ {
+			if counts[a] == 0 || counts[b] == 0 || centres[a].DeltaE(centres[b]) >= mergeDistance {
+				continue
+			}
+			wa, wb := float64(counts[a]), float64(counts[b])
+			centres[a] = Lab{
+				(wa*centres[a].L + wb*centres[b].L) / (wa + wb),
+				(wa*centres[a].A + wb*centres[b].A) / (wa + wb),
+				(wa*centres[a].B + wb*centres[b].B) / (wa + wb),
+			}
+			counts[a] += counts[b]
+			counts[b] = 0
+		}
+	}
+	dominant := make([]DominantColor, 0, len(centres))
+	for k, c := range centres {
+		if counts[k] > 0 {
+			dominant = append(dominant, DominantColor{c.ToPixel(ppm.max), 100 * float64(counts[k]) / float64(total)})
+		}
+	}
+	sort.SliceStable(dominant, func(i, j int) bool {
+		return dominant[i].Coverage > dominant[j].Coverage
+	})
+	return dominant
+}
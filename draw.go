@@ -0,0 +1,904 @@
+package Netpbm
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// LineCap is the shape of the ends of a thick line.
+type LineCap int
+
+const (
+	CapButt   LineCap = iota // the line stops at its end points
+	CapRound                 // a half disk ends the line
+	CapSquare                // the line goes on by half its width
+)
+
+
+// LineJoin is the shape of the corners between the sides of a thick outline.
+type LineJoin int
+
+const (
+	JoinMiter LineJoin = iota // sharp corner, bevelled when longer than miterLimit
+	JoinRound                 // rounded corner
+	JoinBevel                 // cut corner
+)
+
+
+// FillRule tells which points are inside a polygon whose edges cross, or one
+// made of several contours.
+type FillRule int
+
+const (
+	FillEvenOdd FillRule = iota // inside when a ray from the point crosses an odd number of edges
+	FillNonZero                 // inside when the edges around the point do not cancel out
+)
+
+
+// miterLimit is the longest miter, in half line widths, like in SVG
+const miterLimit = 4
+
+
+// StrokeOptions configures the lines drawn by DrawLine, DrawRectangle,
+// DrawTriangle and DrawPolygon.
+type StrokeOptions struct {
+	Width     float64 // in pixels, 1 when 0
+	Cap       LineCap // ends of DrawLine; the other outlines are closed
+	Join      LineJoin
+	AntiAlias bool // blend the edges with the background, ignored by bitmaps
+}
+
+
+// strokeOptions returns the options of a variadic call, with the defaults
+func strokeOptions(opts []StrokeOptions) StrokeOptions {
+	var o StrokeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Width <= 0 {
+		o.Width = 1
+	}
+	return o
+}
+
+
+// plotter draws on an image, blend mixing the colour into pixel (x, y) with a
+// coverage from 0 to 1
+type plotter struct {
+	clip  Rectangle // pixels outside are left untouched
+	blend func(x, y int, coverage float64)
+}
+
+
+// set draws a pixel, ignoring the ones outside the clip rectangle
+func (p plotter) set(x, y int, coverage float64) {
+	if coverage <= 0 || x < p.clip.X || x >= p.clip.X+p.clip.Width || y < p.clip.Y || y >= p.clip.Y+p.clip.Height {
+		return
+	}
+	p.blend(x, y, math.Min(coverage, 1))
+}
+
+
+// clipRect returns the part of the image drawing is restricted to
+func clipRect(clip *Rectangle, width, height int) Rectangle {
+	if clip == nil {
+		return Rectangle{0, 0, width, height}
+	}
+	return clip.clip(width, height)
+}
+
+
+// mix moves a sample towards another by a fraction
+func mix(from, to uint8, fraction float64) uint8 {
+	return uint8(math.Round(float64(from) + (float64(to)-float64(from))*fraction))
+}
+
+
+func (ppm *PPM) plotter(color Pixel) plotter {
+	return plotter{clipRect(ppm.clip, ppm.width, ppm.height), func(x, y int, coverage float64) {
+		p := ppm.data[y][x]
+		ppm.data[y][x] = Pixel{mix(p.R, color.R, coverage), mix(p.G, color.G, coverage), mix(p.B, color.B, coverage)}
+	}}
+}
+
+
+func (pgm *PGM) plotter(value uint8) plotter {
+	return plotter{clipRect(pgm.clip, pgm.width, pgm.height), func(x, y int, coverage float64) {
+		pgm.data[y][x] = mix(pgm.data[y][x], value, coverage)
+	}}
+}
+
+
+// pixels of a bitmap are set when they are at least half covered
+func (pbm *PBM) plotter(value bool) plotter {
+	return plotter{clipRect(pbm.clip, pbm.width, pbm.height), func(x, y int, coverage float64) {
+		if coverage >= 0.5 {
+			pbm.data[y][x] = value
+		}
+	}}
+}
+
+
+// Cohen-Sutherland outcodes, telling on which sides of the clip rectangle a
+// point lies
+const (
+	outLeft = 1 << iota
+	outRight
+	outTop
+	outBottom
+)
+
+
+// outcode returns the sides of [low, high] a point is past
+func outcode(p, low, high vec) int {
+	code := 0
+	if p.X < low.X {
+		code |= outLeft
+	} else if p.X > high.X {
+		code |= outRight
+	}
+	if p.Y < low.Y {
+		code |= outTop
+	} else if p.Y > high.Y {
+		code |= outBottom
+	}
+	return code
+}
+
+
+// clipSegment clips the segment a-b to the rectangle [low, high] with the
+// Cohen-Sutherland algorithm: the segment is kept when both ends are inside,
+// dropped when both are past the same side, and cut at a side otherwise. It
+// returns false when nothing is left.
+func clipSegment(a, b, low, high vec) (vec, vec, bool) {
+	ca, cb := outcode(a, low, high), outcode(b, low, high)
+	for {
+		if ca|cb == 0 {
+			return a, b, true
+		}
+		if ca&cb != 0 {
+			return a, b, false
+		}
+		code := ca
+		if code == 0 {
+			code = cb
+		}
+		var p vec
+		switch {
+		case code&outTop != 0:
+			p = vec{a.X + (b.X-a.X)*(low.Y-a.Y)/(b.Y-a.Y), low.Y}
+		case code&outBottom != 0:
+			p = vec{a.X + (b.X-a.X)*(high.Y-a.Y)/(b.Y-a.Y), high.Y}
+		case code&outLeft != 0:
+			p = vec{low.X, a.Y + (b.Y-a.Y)*(low.X-a.X)/(b.X-a.X)}
+		default:
+			p = vec{high.X, a.Y + (b.Y-a.Y)*(high.X-a.X)/(b.X-a.X)}
+		}
+		if code == ca {
+			a, ca = p, outcode(p, low, high)
+		} else {
+			b, cb = p, outcode(p, low, high)
+		}
+	}
+}
+
+
+// thinLine draws a line one pixel wide. At every step along its major axis it
+// covers the nearest pixel across, or with anti-aliasing the two nearest in
+// proportion to their distance to the line, as in Xiaolin Wu's algorithm.
+// Only the steps inside the clip rectangle are taken, so far away end points
+// cost nothing.
+func thinLine(p plotter, a, b Point, antiAlias bool) {
+	r := p.clip
+	if r.Width == 0 || r.Height == 0 {
+		return
+	}
+	// a pixel of margin for the second pixel of anti-aliased lines
+	from, to := vec{float64(a.X), float64(a.Y)}, vec{float64(b.X), float64(b.Y)}
+	ca, cb, ok := clipSegment(from, to, vec{float64(r.X - 1), float64(r.Y - 1)}, vec{float64(r.X + r.Width), float64(r.Y + r.Height)})
+	if !ok {
+		return
+	}
+	steep := math.Abs(to.Y-from.Y) > math.Abs(to.X-from.X)
+	if steep {
+		from, to, ca, cb = vec{from.Y, from.X}, vec{to.Y, to.X}, vec{ca.Y, ca.X}, vec{cb.Y, cb.X}
+	}
+	gradient := 0.0
+	if to.X != from.X {
+		gradient = (to.Y - from.Y) / (to.X - from.X)
+	}
+	plot := func(x, y int, coverage float64) {
+		if steep {
+			x, y = y, x
+		}
+		p.set(x, y, coverage)
+	}
+	start := int(math.Ceil(math.Min(ca.X, cb.X) - 1e-9))
+	end := int(math.Floor(math.Max(ca.X, cb.X) + 1e-9))
+	for x := start; x <= end; x++ {
+		y := from.Y + gradient*(float64(x)-from.X)
+		if !antiAlias {
+			plot(x, int(math.Round(y)), 1)
+			continue
+		}
+		yi := math.Floor(y)
+		f := y - yi
+		plot(x, int(yi), 1-f)
+		plot(x, int(yi)+1, f)
+	}
+}
+
+
+// span returns the part of [from, to] inside [start, start+length), empty
+// when the first value returned is larger than the second
+func span(from, to, start, length int) (int, int) {
+	if from < start {
+		from = start
+	}
+	if to > start+length-1 {
+		to = start + length - 1
+	}
+	return from, to
+}
+
+
+// vec is a point with float coordinates, pixel centres being at integers
+type vec struct {
+	X, Y float64
+}
+
+func (v vec) add(w vec) vec {
+	return vec{v.X + w.X, v.Y + w.Y}
+}
+
+func (v vec) scale(k float64) vec {
+	return vec{v.X * k, v.Y * k}
+}
+
+
+// shape is a part of a thick line: a convex polygon, or a disk when radius is
+// not 0, hollowed out up to inner for the ring of a circle
+type shape struct {
+	corners []vec
+	center  vec
+	radius  float64
+	inner   float64
+}
+
+
+// contains tells whether a point is inside the shape or on its border
+func (s shape) contains(p vec) bool {
+	if s.radius > 0 {
+		dx, dy := p.X-s.center.X, p.Y-s.center.Y
+		d := dx*dx + dy*dy
+		return d <= s.radius*s.radius && d >= s.inner*s.inner
+	}
+	// the point is on the same side of every edge, whatever their direction
+	sign := 0.0
+	for i, a := range s.corners {
+		b := s.corners[(i+1)%len(s.corners)]
+		c := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+		if c*sign < 0 {
+			return false
+		}
+		if c != 0 {
+			sign = c
+		}
+	}
+	return true
+}
+
+
+// bounds returns the smallest and largest coordinates of the shape
+func (s shape) bounds() (vec, vec) {
+	if s.radius > 0 {
+		r := vec{s.radius, s.radius}
+		return s.center.add(r.scale(-1)), s.center.add(r)
+	}
+	low, high := s.corners[0], s.corners[0]
+	for _, c := range s.corners[1:] {
+		low = vec{math.Min(low.X, c.X), math.Min(low.Y, c.Y)}
+		high = vec{math.Max(high.X, c.X), math.Max(high.Y, c.Y)}
+	}
+	return low, high
+}
+
+
+// strokeShapes splits a thick polyline into shapes: a rectangle along every
+// segment, the caps at the ends of an open line and the joins at its corners
+func strokeShapes(points []Point, closed bool, opts StrokeOptions) []shape {
+	h := opts.Width / 2
+	path := make([]vec, 0, len(points))
+	for i, p := range points {
+		if i == 0 || p != points[i-1] {
+			path = append(path, vec{float64(p.X), float64(p.Y)})
+		}
+	}
+	if closed && len(path) > 1 && path[0] == path[len(path)-1] {
+		path = path[:len(path)-1]
+	}
+	shapes := make([]shape, 0)
+	if len(path) == 1 {
+		// a dot is only visible with caps that go past the end points
+		switch opts.Cap {
+		case CapRound:
+			shapes = append(shapes, shape{center: path[0], radius: h})
+		case CapSquare:
+			c := path[0]
+			shapes = append(shapes, shape{corners: []vec{{c.X - h, c.Y - h}, {c.X + h, c.Y - h}, {c.X + h, c.Y + h}, {c.X - h, c.Y + h}}})
+		}
+		return shapes
+	}
+	if len(path) < 3 {
+		closed = false
+	}
+
+	segments := len(path) - 1
+	if closed {
+		segments++
+	}
+	direction := func(i int) vec {
+		a, b := path[i%len(path)], path[(i+1)%len(path)]
+		d := vec{b.X - a.X, b.Y - a.Y}
+		return d.scale(1 / math.Hypot(d.X, d.Y))
+	}
+	for i := 0; i < segments; i++ {
+		a, b := path[i], path[(i+1)%len(path)]
+		d := direction(i)
+		n := vec{-d.Y, d.X}.scale(h)
+		if !closed && opts.Cap != CapRound {
+			// the ends go a hair past the end points so that, despite the
+			// offset of the samples, the pixels centred on them are covered at
+			// both ends like with thin lines
+			reach := 1.0 / 256
+			if opts.Cap == CapSquare {
+				reach += h
+			}
+			if i == 0 {
+				a = a.add(d.scale(-reach))
+			}
+			if i == segments-1 {
+				b = b.add(d.scale(reach))
+			}
+		}
+		shapes = append(shapes, shape{corners: []vec{a.add(n), b.add(n), b.add(n.scale(-1)), a.add(n.scale(-1))}})
+	}
+	if !closed && opts.Cap == CapRound {
+		shapes = append(shapes, shape{center: path[0], radius: h}, shape{center: path[len(path)-1], radius: h})
+	}
+
+	// joins, at the start of every segment but the first of an open line
+	for i := 0; i < segments; i++ {
+		if !closed && i == 0 {
+			continue
+		}
+		v := path[i]
+		if opts.Join == JoinRound {
+			shapes = append(shapes, shape{center: v, radius: h})
+			continue
+		}
+		d1, d2 := direction(i-1+len(path)), direction(i)
+		turn := d1.X*d2.Y - d1.Y*d2.X
+		if turn == 0 {
+			continue
+		}
+		// normals pointing outside the corner
+		o1, o2 := vec{-d1.Y, d1.X}, vec{-d2.Y, d2.X}
+		if turn > 0 {
+			o1, o2 = o1.scale(-1), o2.scale(-1)
+		}
+		bevel := []vec{v, v.add(o1.scale(h)), v.add(o2.scale(h))}
+		half := math.Hypot(o1.X+o2.X, o1.Y+o2.Y) / 2
+		if opts.Join == JoinBevel || half == 0 || 1/half > miterLimit {
+			shapes = append(shapes, shape{corners: bevel})
+			continue
+		}
+		miter := v.add(o1.add(o2).scale(h / (2 * half * half)))
+		shapes = append(shapes, shape{corners: []vec{bevel[0], bevel[1], miter, bevel[2]}})
+	}
+	return shapes
+}
+
+
+// samples are the positions, relative to the pixel centre, where the coverage
+// of a shape is measured, 4x4 when anti-aliasing. The tiny offset keeps a
+// border through the centres of pixels from covering both rows.
+func samples(antiAlias bool) []vec {
+	const offset = 1.0 / 1024
+	if !antiAlias {
+		return []vec{{offset, offset}}
+	}
+	points := make([]vec, 0, 16)
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			points = append(points, vec{(float64(i)+0.5)/4 - 0.5 + offset, (float64(j)+0.5)/4 - 0.5 + offset})
+		}
+	}
+	return points
+}
+
+
+// fillShapes draws the union of shapes, every pixel being covered in
+// proportion to its samples inside any of them
+func fillShapes(p plotter, shapes []shape, antiAlias bool) {
+	if len(shapes) == 0 {
+		return
+	}
+	low, high := shapes[0].bounds()
+	for _, s := range shapes[1:] {
+		l, h := s.bounds()
+		low = vec{math.Min(low.X, l.X), math.Min(low.Y, l.Y)}
+		high = vec{math.Max(high.X, h.X), math.Max(high.Y, h.Y)}
+	}
+	c := p.clip
+	x0, y0 := int(math.Max(math.Floor(low.X), float64(c.X))), int(math.Max(math.Floor(low.Y), float64(c.Y)))
+	x1, y1 := int(math.Min(math.Ceil(high.X), float64(c.X+c.Width-1))), int(math.Min(math.Ceil(high.Y), float64(c.Y+c.Height-1)))
+	if x0 > x1 || y0 > y1 {
+		return
+	}
+
+	// one bit per sample, so that overlapping shapes are not counted twice
+	points := samples(antiAlias)
+	masks := make([][]uint16, y1-y0+1)
+	for y := range masks {
+		masks[y] = make([]uint16, x1-x0+1)
+	}
+	for _, s := range shapes {
+		l, h := s.bounds()
+		for y := int(math.Max(math.Floor(l.Y), float64(y0))); y <= int(math.Min(math.Ceil(h.Y), float64(y1))); y++ {
+			for x := int(math.Max(math.Floor(l.X), float64(x0))); x <= int(math.Min(math.Ceil(h.X), float64(x1))); x++ {
+				for i, o := range points {
+					if s.contains(vec{float64(x) + o.X, float64(y) + o.Y}) {
+						masks[y-y0][x-x0] |= 1 << i
+					}
+				}
+			}
+		}
+	}
+	for y, row := range masks {
+		for x, m := range row {
+			p.set(x0+x, y0+y, float64(bits.OnesCount16(m))/float64(len(points)))
+		}
+	}
+}
+
+
+// stroke draws a polyline, closed back to its first point for outlines
+func stroke(p plotter, points []Point, closed bool, opts StrokeOptions) {
+	if len(points) == 0 {
+		return
+	}
+	if opts.Width > 1 {
+		fillShapes(p, strokeShapes(points, closed, opts), opts.AntiAlias)
+		return
+	}
+	if len(points) == 1 {
+		thinLine(p, points[0], points[0], opts.AntiAlias)
+		return
+	}
+	for i := 0; i+1 < len(points); i++ {
+		thinLine(p, points[i], points[i+1], opts.AntiAlias)
+	}
+	if closed && len(points) > 2 {
+		thinLine(p, points[len(points)-1], points[0], opts.AntiAlias)
+	}
+}
+
+
+// rectangleCorners returns the corners of a rectangle outline, clockwise
+func rectangleCorners(p1 Point, width, height int) []Point {
+	return []Point{p1, {p1.X + width, p1.Y}, {p1.X + width, p1.Y + height}, {p1.X, p1.Y + height}}
+}
+
+
+// fillRectangle draws a filled rectangle of width x height pixels
+func fillRectangle(p plotter, p1 Point, width, height int) {
+	x0, x1 := span(p1.X, p1.X+width-1, p.clip.X, p.clip.Width)
+	y0, y1 := span(p1.Y, p1.Y+height-1, p.clip.Y, p.clip.Height)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			p.set(x, y, 1)
+		}
+	}
+}
+
+
+// strokeCircle draws the outline of a circle: with the midpoint algorithm when
+// it is one pixel wide, else as a ring straddling the radius
+func strokeCircle(p plotter, center Point, radius int, opts StrokeOptions) {
+	if radius < 0 {
+		return
+	}
+	if opts.Width > 1 || opts.AntiAlias {
+		h := math.Max(opts.Width, 1) / 2
+		r := float64(radius)
+		ring := shape{center: vec{float64(center.X), float64(center.Y)}, radius: r + h, inner: math.Max(r-h, 0)}
+		fillShapes(p, []shape{ring}, opts.AntiAlias)
+		return
+	}
+	x, y, d := radius, 0, 1-radius
+	for y <= x {
+		// the same step in every octant
+		for _, o := range []Point{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+			p.set(center.X+o.X, center.Y+o.Y, 1)
+		}
+		y++
+		if d < 0 {
+			d += 2*y + 1
+		} else {
+			x--
+			d += 2*(y-x) + 1
+		}
+	}
+}
+
+
+// fillCircle draws the pixels around center whose squared distance to it is at
+// most reach, reach being at most radius*radius
+func fillCircle(p plotter, center Point, radius, reach int) {
+	if radius < 0 {
+		return
+	}
+	x0, x1 := span(center.X-radius, center.X+radius, p.clip.X, p.clip.Width)
+	y0, y1 := span(center.Y-radius, center.Y+radius, p.clip.Y, p.clip.Height)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			dx, dy := x-center.X, y-center.Y
+			if dx*dx+dy*dy <= reach {
+				p.set(x, y, 1)
+			}
+		}
+	}
+}
+
+
+// sierpinski draws a Sierpinski triangle of n levels standing on start, with a
+// base of width pixels: the triangle itself at level 0, else the three halves
+// at its corners, one level lower
+func sierpinski(p plotter, n int, start Point, width int) {
+	height := int(float64(width) * math.Sqrt(3) / 2)
+	if n <= 0 {
+		fillPolygon(p, [][]Point{{start, {start.X + width, start.Y}, {start.X + width/2, start.Y - height}}}, FillEvenOdd)
+		return
+	}
+	half := width / 2
+	sierpinski(p, n-1, start, half)
+	sierpinski(p, n-1, Point{start.X + half, start.Y}, half)
+	sierpinski(p, n-1, Point{start.X + width/4, start.Y - height/2}, half)
+}
+
+
+// fillRule returns the rule of a variadic call, even-odd by default
+func fillRule(rules []FillRule) FillRule {
+	if len(rules) > 0 {
+		return rules[0]
+	}
+	return FillEvenOdd
+}
+
+
+// polygonEdge is an edge of a polygon that is not horizontal, from its top to
+// its bottom
+type polygonEdge struct {
+	top, bottom int     // first line it crosses and the line after the last
+	x, y        float64 // its top point
+	slope       float64 // change of x for a line down
+	winding     int     // 1 when the contour goes down along it, -1 when up
+}
+
+
+// crossing is where an edge crosses a line
+type crossing struct {
+	x       float64
+	winding int
+}
+
+
+// fillPolygon draws the inside of polygons made of one or more contours with
+// an active edge table. The edges, sorted by their top, join the active list
+// when the scanline reaches them and leave it past their bottom; on every line
+// the crossings of the active edges are sorted and the spans between them that
+// are inside for the rule are filled. A pixel is inside when its centre is.
+// Lines and spans are cut to the clip rectangle before being walked.
+func fillPolygon(p plotter, contours [][]Point, rule FillRule) {
+	edges := make([]polygonEdge, 0)
+	bottom := 0
+	for _, contour := range contours {
+		if len(contour) < 3 {
+			continue
+		}
+		for i, a := range contour {
+			b := contour[(i+1)%len(contour)]
+			if a.Y == b.Y {
+				continue
+			}
+			winding := 1
+			if a.Y > b.Y {
+				a, b = b, a
+				winding = -1
+			}
+			edges = append(edges, polygonEdge{a.Y, b.Y, float64(a.X), float64(a.Y), float64(b.X-a.X) / float64(b.Y-a.Y), winding})
+			if len(edges) == 1 || b.Y > bottom {
+				bottom = b.Y
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].top < edges[j].top
+	})
+
+	// lines are sampled a little below the centres of the pixels, so that
+	// vertices never fall on them
+	const offset = 1.0 / 1024
+	y0, y1 := span(edges[0].top, bottom-1, p.clip.Y, p.clip.Height)
+	active := make([]polygonEdge, 0)
+	crossings := make([]crossing, 0)
+	next := 0
+	for y := y0; y <= y1; y++ {
+		for next < len(edges) && edges[next].top <= y {
+			active = append(active, edges[next])
+			next++
+		}
+		kept := active[:0]
+		for _, e := range active {
+			if e.bottom > y {
+				kept = append(kept, e)
+			}
+		}
+		active = kept
+
+		crossings = crossings[:0]
+		for _, e := range active {
+			crossings = append(crossings, crossing{e.x + (float64(y)+offset-e.y)*e.slope, e.winding})
+		}
+		sort.Slice(crossings, func(i, j int) bool {
+			return crossings[i].x < crossings[j].x
+		})
+		inside := 0
+		for i := 0; i+1 < len(crossings); i++ {
+			if rule == FillNonZero {
+				inside += crossings[i].winding
+			} else {
+				inside ^= 1
+			}
+			if inside == 0 {
+				continue
+			}
+			// the pixels whose centre is in [x0, x1), within the clip rectangle
+			from := math.Max(math.Ceil(crossings[i].x-offset), float64(p.clip.X))
+			to := math.Min(math.Ceil(crossings[i+1].x-offset)-1, float64(p.clip.X+p.clip.Width-1))
+			for x := int(from); float64(x) <= to; x++ {
+				p.set(x, y, 1)
+			}
+		}
+	}
+}
+
+
+// DrawLine draws a line of one pixel on the graymap. See PPM.DrawLine.
+func (pgm *PGM) DrawLine(p1, p2 Point, value uint8, opts ...StrokeOptions) {
+	stroke(pgm.plotter(value), []Point{p1, p2}, false, strokeOptions(opts))
+}
+
+
+// DrawRectangle draws the outline of a rectangle on the graymap, from p1 to
+// p1 + (width, height).
+func (pgm *PGM) DrawRectangle(p1 Point, width, height int, value uint8, opts ...StrokeOptions) {
+	stroke(pgm.plotter(value), rectangleCorners(p1, width, height), true, strokeOptions(opts))
+}
+
+
+// DrawFilledRectangle fills width x height pixels of the graymap from p1.
+func (pgm *PGM) DrawFilledRectangle(p1 Point, width, height int, value uint8) {
+	fillRectangle(pgm.plotter(value), p1, width, height)
+}
+
+
+// DrawTriangle draws the outline of a triangle on the graymap.
+func (pgm *PGM) DrawTriangle(p1, p2, p3 Point, value uint8, opts ...StrokeOptions) {
+	stroke(pgm.plotter(value), []Point{p1, p2, p3}, true, strokeOptions(opts))
+}
+
+
+// DrawFilledTriangle fills a triangle of the graymap.
+func (pgm *PGM) DrawFilledTriangle(p1, p2, p3 Point, value uint8) {
+	fillPolygon(pgm.plotter(value), [][]Point{{p1, p2, p3}}, FillEvenOdd)
+}
+
+
+// DrawPolygon draws the outline of a polygon on the graymap, its last point
+// being joined to the first.
+func (pgm *PGM) DrawPolygon(points []Point, value uint8, opts ...StrokeOptions) {
+	stroke(pgm.plotter(value), points, true, strokeOptions(opts))
+}
+
+
+// DrawFilledPolygon fills a polygon of the graymap. See PPM.DrawFilledPolygon.
+func (pgm *PGM) DrawFilledPolygon(points []Point, value uint8, rule ...FillRule) {
+	fillPolygon(pgm.plotter(value), [][]Point{points}, fillRule(rule))
+}
+
+
+// DrawFilledContours fills a polygon of several contours on the graymap. See
+// PPM.DrawFilledContours.
+func (pgm *PGM) DrawFilledContours(contours [][]Point, value uint8, rule ...FillRule) {
+	fillPolygon(pgm.plotter(value), contours, fillRule(rule))
+}
+
+
+// DrawCircle draws a disk of the given radius around center on the graymap.
+// See PPM.DrawCircle.
+func (pgm *PGM) DrawCircle(center Point, radius int, value uint8) {
+	fillCircle(pgm.plotter(value), center, radius, radius*radius)
+}
+
+
+// DrawCircleOutline draws the outline of a circle on the graymap. See
+// PPM.DrawCircleOutline.
+func (pgm *PGM) DrawCircleOutline(center Point, radius int, value uint8, opts ...StrokeOptions) {
+	strokeCircle(pgm.plotter(value), center, radius, strokeOptions(opts))
+}
+
+
+// DrawFilledCircle fills the pixels of the graymap less than radius+1 away
+// from center.
+func (pgm *PGM) DrawFilledCircle(center Point, radius int, value uint8) {
+	fillCircle(pgm.plotter(value), center, radius, (radius+1)*(radius+1)-1)
+}
+
+
+// DrawSierpinskiTriangle draws a Sierpinski triangle on the graymap. See
+// PPM.DrawSierpinskiTriangle.
+func (pgm *PGM) DrawSierpinskiTriangle(n int, start Point, width int, value uint8) {
+	sierpinski(pgm.plotter(value), n, start, width)
+}
+
+
+// DrawLine draws a line on the bitmap. See PPM.DrawLine; bitmaps are never
+// anti-aliased.
+func (pbm *PBM) DrawLine(p1, p2 Point, value bool, opts ...StrokeOptions) {
+	o := strokeOptions(opts)
+	o.AntiAlias = false
+	stroke(pbm.plotter(value), []Point{p1, p2}, false, o)
+}
+
+
+// DrawRectangle draws the outline of a rectangle on the bitmap, from p1 to
+// p1 + (width, height).
+func (pbm *PBM) DrawRectangle(p1 Point, width, height int, value bool, opts ...StrokeOptions) {
+	o := strokeOptions(opts)
+	o.AntiAlias = false
+	stroke(pbm.plotter(value), rectangleCorners(p1, width, height), true, o)
+}
+
+
+// DrawFilledRectangle fills width x height pixels of the bitmap from p1.
+func (pbm *PBM) DrawFilledRectangle(p1 Point, width, height int, value bool) {
+	fillRectangle(pbm.plotter(value), p1, width, height)
+}
+
+
+// DrawTriangle draws the outline of a triangle on the bitmap.
+func (pbm *PBM) DrawTriangle(p1, p2, p3 Point, value bool, opts ...StrokeOptions) {
+	o := strokeOptions(opts)
+	o.AntiAlias = false
+	stroke(pbm.plotter(value), []Point{p1, p2, p3}, true, o)
+}
+
+
+// DrawFilledTriangle fills a triangle of the bitmap.
+func (pbm *PBM) DrawFilledTriangle(p1, p2, p3 Point, value bool) {
+	fillPolygon(pbm.plotter(value), [][]Point{{p1, p2, p3}}, FillEvenOdd)
+}
+
+
+// DrawPolygon draws the outline of a polygon on the bitmap, its last point
+// being joined to the first.
+func (pbm *PBM) DrawPolygon(points []Point, value bool, opts ...StrokeOptions) {
+	o := strokeOptions(opts)
+	o.AntiAlias = false
+	stroke(pbm.plotter(value), points, true, o)
+}
+
+
+// DrawFilledPolygon fills a polygon of the bitmap. See PPM.DrawFilledPolygon.
+func (pbm *PBM) DrawFilledPolygon(points []Point, value bool, rule ...FillRule) {
+	fillPolygon(pbm.plotter(value), [][]Point{points}, fillRule(rule))
+}
+
+
+// DrawFilledContours fills a polygon of several contours on the bitmap. See
+// PPM.DrawFilledContours.
+func (pbm *PBM) DrawFilledContours(contours [][]Point, value bool, rule ...FillRule) {
+	fillPolygon(pbm.plotter(value), contours, fillRule(rule))
+}
+
+
+// DrawCircle draws a disk of the given radius around center on the bitmap. See
+// PPM.DrawCircle.
+func (pbm *PBM) DrawCircle(center Point, radius int, value bool) {
+	fillCircle(pbm.plotter(value), center, radius, radius*radius)
+}
+
+
+// DrawCircleOutline draws the outline of a circle on the bitmap. See
+// PPM.DrawCircleOutline; bitmaps are never anti-aliased.
+func (pbm *PBM) DrawCircleOutline(center Point, radius int, value bool, opts ...StrokeOptions) {
+	o := strokeOptions(opts)
+	o.AntiAlias = false
+	strokeCircle(pbm.plotter(value), center, radius, o)
+}
+
+
+// DrawFilledCircle fills the pixels of the bitmap less than radius+1 away from
+// center.
+func (pbm *PBM) DrawFilledCircle(center Point, radius int, value bool) {
+	fillCircle(pbm.plotter(value), center, radius, (radius+1)*(radius+1)-1)
+}
+
+
+// DrawSierpinskiTriangle draws a Sierpinski triangle on the bitmap. See
+// PPM.DrawSierpinskiTriangle.
+func (pbm *PBM) DrawSierpinskiTriangle(n int, start Point, width int, value bool) {
+	sierpinski(pbm.plotter(value), n, start, width)
+}
+
+
+// SetClip restricts drawing to the rectangle r: the drawing methods, FloodFill
+// and Composite onto the pixmap leave the pixels outside it untouched. It does
+// not change the other operations, such as filters and transforms.
+func (ppm *PPM) SetClip(r Rectangle) {
+	ppm.clip = &r
+}
+
+
+// ResetClip lets drawing reach the whole pixmap again.
+func (ppm *PPM) ResetClip() {
+	ppm.clip = nil
+}
+
+
+// Clip returns the part of the pixmap drawing is restricted to.
+func (ppm *PPM) Clip() Rectangle {
+	return clipRect(ppm.clip, ppm.width, ppm.height)
+}
+
+
+// SetClip restricts drawing to the rectangle r. See PPM.SetClip.
+func (pgm *PGM) SetClip(r Rectangle) {
+	pgm.clip = &r
+}
+
+
+// ResetClip lets drawing reach the whole graymap again.
+func (pgm *PGM) ResetClip() {
+	pgm.clip = nil
+}
+
+
+// Clip returns the part of the graymap drawing is restricted to.
+func (pgm *PGM) Clip() Rectangle {
+	return clipRect(pgm.clip, pgm.width, pgm.height)
+}
+
+
+// SetClip restricts drawing to the rectangle r. See PPM.SetClip.
+func (pbm *PBM) SetClip(r Rectangle) {
+	pbm.clip = &r
+}
+
+
+// ResetClip lets drawing reach the whole bitmap again.
+func (pbm *PBM) ResetClip() {
+	pbm.clip = nil
+}
+
+
+// Clip returns the part of the bitmap drawing is restricted to.
+func (pbm *PBM) Clip() Rectangle {
+	return clipRect(pbm.clip, pbm.width, pbm.height)
+}
@@ -0,0 +1,167 @@
+package Netpbm
+
+import "testing"
+
+func TestDrawShapesAgree(t *testing.T) {
+	draws := []struct {
+		name string
+		ppm  func(*PPM)
+		pgm  func(*PGM)
+		pbm  func(*PBM)
+	}{
+		{"circle outline",
+			func(i *PPM) { i.DrawCircleOutline(Point{10, 10}, 7, Pixel{255, 255, 255}) },
+			func(i *PGM) { i.DrawCircleOutline(Point{10, 10}, 7, 255) },
+			func(i *PBM) { i.DrawCircleOutline(Point{10, 10}, 7, true) }},
+		{"thick circle",
+			func(i *PPM) { i.DrawCircleOutline(Point{10, 10}, 6, Pixel{255, 255, 255}, StrokeOptions{Width: 3}) },
+			func(i *PGM) { i.DrawCircleOutline(Point{10, 10}, 6, 255, StrokeOptions{Width: 3}) },
+			func(i *PBM) { i.DrawCircleOutline(Point{10, 10}, 6, true, StrokeOptions{Width: 3}) }},
+		{"circle",
+			func(i *PPM) { i.DrawCircle(Point{15, 5}, 6, Pixel{255, 255, 255}) },
+			func(i *PGM) { i.DrawCircle(Point{15, 5}, 6, 255) },
+			func(i *PBM) { i.DrawCircle(Point{15, 5}, 6, true) }},
+		{"filled circle",
+			func(i *PPM) { i.DrawFilledCircle(Point{3, 4}, 8, Pixel{255, 255, 255}) },
+			func(i *PGM) { i.DrawFilledCircle(Point{3, 4}, 8, 255) },
+			func(i *PBM) { i.DrawFilledCircle(Point{3, 4}, 8, true) }},
+		{"sierpinski",
+			func(i *PPM) { i.DrawSierpinskiTriangle(2, Point{1, 18}, 16, Pixel{255, 255, 255}) },
+			func(i *PGM) { i.DrawSierpinskiTriangle(2, Point{1, 18}, 16, 255) },
+			func(i *PBM) { i.DrawSierpinskiTriangle(2, Point{1, 18}, 16, true) }},
+	}
+	for _, d := range draws {
+		ppm, pgm, pbm := NewPPM(20, 20, 255), NewPGM(20, 20, 255), NewPBM(20, 20)
+		clip := Rectangle{2, 2, 14, 15}
+		ppm.SetClip(clip)
+		pgm.SetClip(clip)
+		pbm.SetClip(clip)
+		d.ppm(ppm)
+		d.pgm(pgm)
+		d.pbm(pbm)
+		count := 0
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				on := pgm.data[y][x] == 255
+				if on {
+					count++
+				}
+				if on != pbm.data[y][x] || on != (ppm.data[y][x] == Pixel{255, 255, 255}) {
+					t.Errorf("%s: the images differ at (%d, %d)", d.name, x, y)
+				}
+				if on && (x < clip.X || x >= clip.X+clip.Width || y < clip.Y || y >= clip.Y+clip.Height) {
+					t.Errorf("%s: (%d, %d) is outside the clip rectangle", d.name, x, y)
+				}
+			}
+		}
+		if count == 0 {
+			t.Errorf("%s: nothing drawn", d.name)
+		}
+	}
+}
+
+
+func TestDrawCircle(t *testing.T) {
+	// DrawCircle draws a disk up to the radius included, DrawFilledCircle one
+	// reaching less than a pixel further
+	pgm := NewPGM(21, 21, 255)
+	pgm.DrawCircle(Point{10, 10}, 3, 255)
+	if pgm.data[10][10] != 255 || pgm.data[10][13] != 255 || pgm.data[12][13] != 0 {
+		t.Errorf("got row %v and (13, 12) %d", pgm.data[10], pgm.data[12][13])
+	}
+	pgm = NewPGM(21, 21, 255)
+	pgm.DrawFilledCircle(Point{10, 10}, 3, 255)
+	if pgm.data[10][14] != 0 || pgm.data[12][13] != 255 || pgm.data[13][13] != 0 {
+		t.Errorf("got row %v and (13, 12) %d", pgm.data[10], pgm.data[12][13])
+	}
+
+	// an outline one pixel wide goes through the four ends of its diameters and
+	// leaves its centre empty
+	pgm = NewPGM(21, 21, 255)
+	pgm.DrawCircleOutline(Point{10, 10}, 6, 255)
+	for _, p := range []Point{{16, 10}, {4, 10}, {10, 16}, {10, 4}} {
+		if pgm.data[p.Y][p.X] != 255 {
+			t.Errorf("%v is not drawn", p)
+		}
+	}
+	if pgm.data[10][10] != 0 || pgm.data[10][15] != 0 || pgm.data[10][17] != 0 {
+		t.Errorf("the circle is not one pixel wide: %v", pgm.data[10])
+	}
+
+	// a thick one straddles the radius
+	pgm = NewPGM(21, 21, 255)
+	pgm.DrawCircleOutline(Point{10, 10}, 6, 255, StrokeOptions{Width: 3})
+	for x, want := range map[int]uint8{2: 0, 3: 255, 4: 255, 5: 255, 6: 0, 10: 0, 14: 0, 15: 255, 16: 255, 17: 255, 18: 0} {
+		if pgm.data[10][x] != want {
+			t.Errorf("(%d, 10) is %d, want %d", x, pgm.data[10][x], want)
+		}
+	}
+
+	// anti-aliasing leaves pixels partly covered
+	pgm = NewPGM(21, 21, 255)
+	pgm.DrawCircleOutline(Point{10, 10}, 6, 255, StrokeOptions{AntiAlias: true})
+	partial := false
+	for _, row := range pgm.data {
+		for _, v := range row {
+			partial = partial || (v > 0 && v < 255)
+		}
+	}
+	if !partial {
+		t.Error("the anti-aliased circle has no partly covered pixel")
+	}
+}
+
+
+func TestDrawFilledContoursRule(t *testing.T) {
+	// two squares going round the same way: a hole with the default even-odd
+	// rule, filled with the non-zero rule
+	contours := [][]Point{{{1, 1}, {9, 1}, {9, 9}, {1, 9}}, {{4, 4}, {6, 4}, {6, 6}, {4, 6}}}
+	pbm := NewPBM(11, 11)
+	pbm.DrawFilledContours(contours, true)
+	if !pbm.data[2][2] || pbm.data[5][5] {
+		t.Error("the even-odd rule does not leave a hole")
+	}
+	pbm = NewPBM(11, 11)
+	pbm.DrawFilledContours(contours, true, FillNonZero)
+	if !pbm.data[2][2] || !pbm.data[5][5] {
+		t.Error("the non-zero rule leaves a hole")
+	}
+}
+
+
+func TestThickLineEnds(t *testing.T) {
+	// a thick line covers the same pixels along its length as a thin one, from
+	// p1 to p2 included, whatever its cap
+	extent := func(p1, p2 Point, opts ...StrokeOptions) (int, int) {
+		pbm := NewPBM(20, 20)
+		pbm.DrawLine(p1, p2, true, opts...)
+		first, last := -1, -1
+		for i := 0; i < 20; i++ {
+			on := pbm.data[10][i]
+			if p1.X == p2.X {
+				on = pbm.data[i][10]
+			}
+			if on {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		return first, last
+	}
+	for _, line := range [][2]Point{{{2, 10}, {17, 10}}, {{10, 2}, {10, 17}}, {{17, 10}, {2, 10}}} {
+		first, last := extent(line[0], line[1])
+		if first != 2 || last != 17 {
+			t.Errorf("thin line %v covers %d to %d", line, first, last)
+		}
+		for _, width := range []float64{2, 3, 4} {
+			if f, l := extent(line[0], line[1], StrokeOptions{Width: width}); f != first || l != last {
+				t.Errorf("line %v of width %g covers %d to %d, want %d to %d", line, width, f, l, first, last)
+			}
+			if f, l := extent(line[0], line[1], StrokeOptions{Width: width, Cap: CapSquare}); f != first-int(width/2) || l != last+int(width/2) {
+				t.Errorf("square-capped line %v of width %g covers %d to %d", line, width, f, l)
+			}
+		}
+	}
+}
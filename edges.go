@@ -0,0 +1,226 @@
+package Netpbm
+
+import "math"
+
+// EdgeOperator selects the gradient operator of Edges.
+type EdgeOperator int
+
+const (
+	EdgeSobel EdgeOperator = iota
+	EdgePrewitt
+	EdgeScharr
+	EdgeLaplacian // second derivative, has no direction
+)
+
+
+// edgeKernels gives the horizontal derivative kernel of every first order
+// operator and the weight of a step from 0 to 1 through it. The vertical
+// kernel is its transpose.
+var edgeKernels = map[EdgeOperator]struct {
+	kernel [][]float64
+	scale  float64
+}{
+	EdgeSobel:   {[][]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}}, 4},
+	EdgePrewitt: {[][]float64{{-1, 0, 1}, {-1, 0, 1}, {-1, 0, 1}}, 3},
+	EdgeScharr:  {[][]float64{{-3, 0, 3}, {-10, 0, 10}, {-3, 0, 3}}, 16},
+}
+
+
+// gradient returns the horizontal and vertical derivatives of a plane, scaled
+// so that a step of 1 gives 1
+func gradient(plane [][]float64, op EdgeOperator) ([][]float64, [][]float64) {
+	k := edgeKernels[op]
+	if k.kernel == nil {
+		k = edgeKernels[EdgeSobel]
+	}
+	gx := convolvePlane(plane, k.kernel, BorderClamp)
+	gy := convolvePlane(plane, transpose(k.kernel), BorderClamp)
+	for y := range gx {
+		for x := range gx[y] {
+			gx[y][x] /= k.scale
+			gy[y][x] /= k.scale
+		}
+	}
+	return gx, gy
+}
+
+
+// edges computes the magnitude and direction images of a plane of samples
+// going from 0 to max
+func edges(plane [][]float64, max int, op EdgeOperator) (*PGM, *PGM) {
+	height := len(plane)
+	width := 0
+	if height > 0 {
+		width = len(plane[0])
+	}
+	magnitude := NewPGM(width, height, max)
+	if op == EdgeLaplacian {
+		l := convolvePlane(plane, [][]float64{{0, 1, 0}, {1, -4, 1}, {0, 1, 0}}, BorderClamp)
+		for y := range l {
+			for x := range l[y] {
+				l[y][x] = math.Abs(l[y][x]) / 4
+			}
+		}
+		magnitude.setPlane(l)
+		return magnitude, nil
+	}
+
+	direction := NewPGM(width, height, max)
+	gx, gy := gradient(plane, op)
+	m := make([][]float64, height)
+	d := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		m[y] = make([]float64, width)
+		d[y] = make([]float64, width)
+		for x := 0; x < width; x++ {
+			m[y][x] = math.Hypot(gx[y][x], gy[y][x])
+			// y goes down in images, make angles counter-clockwise
+			angle := math.Atan2(-gy[y][x], gx[y][x]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 360
+			}
+			d[y][x] = angle / 360 * float64(max)
+		}
+	}
+	magnitude.setPlane(m)
+	direction.setPlane(d)
+	return magnitude, direction
+}
+
+
+// Edges returns the gradient magnitude of the graymap, a step from black to
+// maxval giving maxval, and the gradient direction, 0 to maxval standing for
+// 0 to 360 degrees counter-clockwise from the right. The direction is nil for
+// the Laplacian.
+func (pgm *PGM) Edges(op EdgeOperator) (*PGM, *PGM) {
+	return edges(pgm.plane(), pgm.max, op)
+}
+
+
+// Edges returns the gradient magnitude and direction of the luma of the
+// pixmap. See PGM.Edges.
+func (ppm *PPM) Edges(op EdgeOperator) (*PGM, *PGM) {
+	return ppm.ToPGM().Edges(op)
+}
+
+
+// CannyOptions configures the Canny edge detector.
+type CannyOptions struct {
+	Sigma float64 // Gaussian smoothing applied first, 1.4 when 0
+	Low   float64 // weak edge threshold, as a fraction of the maxval, 0.1 when 0
+	High  float64 // strong edge threshold, as a fraction of the maxval, 0.2 when 0
+}
+
+
+// canny runs the Canny detector on a plane of samples going from 0 to max
+func canny(plane [][]float64, max int, opts CannyOptions) *PBM {
+	sigma, low, high := opts.Sigma, opts.Low, opts.High
+	if sigma <= 0 {
+		sigma = 1.4
+	}
+	if low <= 0 {
+		low = 0.1
+	}
+	if high <= 0 {
+		high = 0.2
+	}
+	low *= float64(max)
+	high *= float64(max)
+
+	height := len(plane)
+	width := 0
+	if height > 0 {
+		width = len(plane[0])
+	}
+	gx, gy := gradient(gaussianBlur(sigma)(plane), EdgeSobel)
+	m := make([][]float64, height)
+	for y := range m {
+		m[y] = make([]float64, width)
+		for x := range m[y] {
+			m[y][x] = math.Hypot(gx[y][x], gy[y][x])
+		}
+	}
+	at := func(x, y int) float64 {
+		if x < 0 || x >= width || y < 0 || y >= height {
+			return 0
+		}
+		return m[y][x]
+	}
+
+	// keep only the local maxima across the edge, then classify them
+	const (
+		none = iota
+		weak
+		strong
+	)
+	class := make([][]int, height)
+	for y := 0; y < height; y++ {
+		class[y] = make([]int, width)
+		for x := 0; x < width; x++ {
+			v := m[y][x]
+			if v < low {
+				continue
+			}
+			angle := math.Mod(math.Atan2(gy[y][x], gx[y][x])*180/math.Pi+180, 180)
+			var dx, dy int
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				dx, dy = 1, 0
+			case angle < 67.5:
+				dx, dy = 1, 1
+			case angle < 112.5:
+				dx, dy = 0, 1
+			default:
+				dx, dy = -1, 1
+			}
+			if v < at(x+dx, y+dy) || v < at(x-dx, y-dy) {
+				continue
+			}
+			if v >= high {
+				class[y][x] = strong
+			} else {
+				class[y][x] = weak
+			}
+		}
+	}
+
+	// hysteresis: weak edges are kept when connected to a strong one
+	pbm := NewPBM(width, height)
+	stack := make([]Point, 0)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if class[y][x] == strong {
+				pbm.data[y][x] = true
+				stack = append(stack, Point{x, y})
+			}
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				x, y := p.X+dx, p.Y+dy
+				if x >= 0 && x < width && y >= 0 && y < height && class[y][x] == weak && !pbm.data[y][x] {
+					pbm.data[y][x] = true
+					stack = append(stack, Point{x, y})
+				}
+			}
+		}
+	}
+	return pbm
+}
+
+
+// Canny detects the edges of the graymap with the Canny algorithm: Gaussian
+// smoothing, Sobel gradient, thinning to the local maxima and hysteresis
+// between the two thresholds. Edge pixels are true.
+func (pgm *PGM) Canny(opts CannyOptions) *PBM {
+	return canny(pgm.plane(), pgm.max, opts)
+}
+
+
+// Canny detects the edges of the luma of the pixmap. See PGM.Canny.
+func (ppm *PPM) Canny(opts CannyOptions) *PBM {
+	return ppm.ToPGM().Canny(opts)
+}
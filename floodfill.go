@@ -0,0 +1,170 @@
+package Netpbm
+
+// floodRegion returns the pixels connected to seed for which match is true,
+// found a line at a time: every run of matching pixels is filled at once, then
+// the lines above and below it are searched for new runs
+func floodRegion(width, height int, seed Point, conn Connectivity, match func(x, y int) bool) [][]bool {
+	region := make([][]bool, height)
+	for y := range region {
+		region[y] = make([]bool, width)
+	}
+	if seed.X < 0 || seed.X >= width || seed.Y < 0 || seed.Y >= height || !match(seed.X, seed.Y) {
+		return region
+	}
+	// diagonal neighbours reach one pixel further than the run
+	reach := 1
+	if conn == Connect4 {
+		reach = 0
+	}
+	stack := []Point{seed}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if region[p.Y][p.X] {
+			continue
+		}
+		left, right := p.X, p.X
+		for left > 0 && !region[p.Y][left-1] && match(left-1, p.Y) {
+			left--
+		}
+		for right < width-1 && !region[p.Y][right+1] && match(right+1, p.Y) {
+			right++
+		}
+		for x := left; x <= right; x++ {
+			region[p.Y][x] = true
+		}
+		for _, y := range []int{p.Y - 1, p.Y + 1} {
+			if y < 0 || y >= height {
+				continue
+			}
+			from, to := left-reach, right+reach
+			if from < 0 {
+				from = 0
+			}
+			if to > width-1 {
+				to = width - 1
+			}
+			inRun := false
+			for x := from; x <= to; x++ {
+				if !region[y][x] && match(x, y) {
+					if !inRun {
+						stack = append(stack, Point{x, y})
+						inRun = true
+					}
+				} else {
+					inRun = false
+				}
+			}
+		}
+	}
+	return region
+}
+
+
+// FloodRegion returns as a bitmap the region FloodFill would fill, without
+// changing the image.
+func (pbm *PBM) FloodRegion(seed Point, conn Connectivity) *PBM {
+	mask := NewPBM(pbm.width, pbm.height)
+	if seed.X < 0 || seed.X >= pbm.width || seed.Y < 0 || seed.Y >= pbm.height {
+		return mask
+	}
+	target := pbm.data[seed.Y][seed.X]
+	mask.data = floodRegion(pbm.width, pbm.height, seed, conn, func(x, y int) bool {
+		return pbm.data[y][x] == target
+	})
+	return mask
+}
+
+
+// FloodFill sets to value the pixels of the bitmap connected to seed that have
+// its value, like the paint bucket of a drawing program. As pixels are either
+// on or off there is no tolerance. Like drawing, it leaves the pixels outside
+// the clip rectangle untouched. It returns the number of pixels changed;
+// FloodRegion gives the region itself as a mask.
+func (pbm *PBM) FloodFill(seed Point, value bool, conn Connectivity) int {
+	clip := pbm.Clip()
+	changed := 0
+	for y, row := range pbm.FloodRegion(seed, conn).data {
+		for x, in := range row {
+			if in && clip.contains(x, y) && pbm.data[y][x] != value {
+				pbm.data[y][x] = value
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+
+// FloodRegion returns as a bitmap the region FloodFill would fill, without
+// changing the image.
+func (pgm *PGM) FloodRegion(seed Point, tolerance int, conn Connectivity) *PBM {
+	mask := NewPBM(pgm.width, pgm.height)
+	if seed.X < 0 || seed.X >= pgm.width || seed.Y < 0 || seed.Y >= pgm.height {
+		return mask
+	}
+	target := int(pgm.data[seed.Y][seed.X])
+	mask.data = floodRegion(pgm.width, pgm.height, seed, conn, func(x, y int) bool {
+		d := int(pgm.data[y][x]) - target
+		return d <= tolerance && -d <= tolerance
+	})
+	return mask
+}
+
+
+// FloodFill sets to value the pixels of the graymap connected to seed whose
+// sample differs from the one of seed by at most tolerance, inside the clip
+// rectangle. It returns the number of pixels changed.
+func (pgm *PGM) FloodFill(seed Point, value uint8, tolerance int, conn Connectivity) int {
+	clip := pgm.Clip()
+	changed := 0
+	for y, row := range pgm.FloodRegion(seed, tolerance, conn).data {
+		for x, in := range row {
+			if in && clip.contains(x, y) && pgm.data[y][x] != value {
+				pgm.data[y][x] = value
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+
+// FloodRegion returns as a bitmap the region FloodFill would fill, without
+// changing the image.
+func (ppm *PPM) FloodRegion(seed Point, tolerance int, conn Connectivity) *PBM {
+	mask := NewPBM(ppm.width, ppm.height)
+	if seed.X < 0 || seed.X >= ppm.width || seed.Y < 0 || seed.Y >= ppm.height {
+		return mask
+	}
+	target := ppm.data[seed.Y][seed.X]
+	mask.data = floodRegion(ppm.width, ppm.height, seed, conn, func(x, y int) bool {
+		p := ppm.data[y][x]
+		for c := Red; c <= Blue; c++ {
+			d := int(p.channel(c)) - int(target.channel(c))
+			if d > tolerance || -d > tolerance {
+				return false
+			}
+		}
+		return true
+	})
+	return mask
+}
+
+
+// FloodFill sets to value the pixels of the pixmap connected to seed whose
+// channels all differ from the ones of seed by at most tolerance, inside the
+// clip rectangle. It returns the number of pixels changed.
+func (ppm *PPM) FloodFill(seed Point, value Pixel, tolerance int, conn Connectivity) int {
+	clip := ppm.Clip()
+	changed := 0
+	for y, row := range ppm.FloodRegion(seed, tolerance, conn).data {
+		for x, in := range row {
+			if in && clip.contains(x, y) && ppm.data[y][x] != value {
+				ppm.data[y][x] = value
+				changed++
+			}
+		}
+	}
+	return changed
+}
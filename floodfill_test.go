@@ -0,0 +1,31 @@
+package Netpbm
+
+import "testing"
+
+func TestFloodFillClip(t *testing.T) {
+	clip := Rectangle{2, 2, 4, 3}
+	pbm := NewPBM(8, 8)
+	pbm.SetClip(clip)
+	pgm := NewPGM(8, 8, 255)
+	pgm.SetClip(clip)
+	ppm := NewPPM(8, 8, 255)
+	ppm.SetClip(clip)
+	counts := []int{
+		pbm.FloodFill(Point{0, 0}, true, Connect4),
+		pgm.FloodFill(Point{0, 0}, 255, 0, Connect4),
+		ppm.FloodFill(Point{0, 0}, Pixel{255, 255, 255}, 0, Connect4),
+	}
+	for i, n := range counts {
+		if n != clip.Width*clip.Height {
+			t.Errorf("fill %d changed %d pixels, want %d", i, n, clip.Width*clip.Height)
+		}
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			in := clip.contains(x, y)
+			if pbm.data[y][x] != in || (pgm.data[y][x] == 255) != in || (ppm.data[y][x] == Pixel{255, 255, 255}) != in {
+				t.Errorf("(%d, %d) is wrong", x, y)
+			}
+		}
+	}
+}
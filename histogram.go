@@ -0,0 +1,279 @@
+package Netpbm
+
+import "math"
+
+// Histogram returns the number of pixels of every gray level, from 0 to maxval.
+func (pgm *PGM) Histogram() []int {
+	counts := make([]int, pgm.max+1)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			counts[pgm.data[y][x]]++
+		}
+	}
+	return counts
+}
+
+
+// Histogram returns the histograms of the red, green and blue channels, each
+// going from 0 to maxval.
+func (ppm *PPM) Histogram() [3][]int {
+	var counts [3][]int
+	for c := range counts {
+		counts[c] = make([]int, ppm.max+1)
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			counts[0][p.R]++
+			counts[1][p.G]++
+			counts[2][p.B]++
+		}
+	}
+	return counts
+}
+
+
+// CumulativeHistogram returns, for every level, the number of pixels at or
+// below it.
+func CumulativeHistogram(counts []int) []int {
+	cumulative := make([]int, len(counts))
+	total := 0
+	for v, c := range counts {
+		total += c
+		cumulative[v] = total
+	}
+	return cumulative
+}
+
+
+// equalizeTable returns the lookup table spreading the histogram over 0 to max
+func equalizeTable(counts []int, max int) []int {
+	cumulative := CumulativeHistogram(counts)
+	total := cumulative[len(cumulative)-1]
+	lowest := 0
+	for _, c := range cumulative {
+		if c > 0 {
+			lowest = c
+			break
+		}
+	}
+	table := make([]int, len(counts))
+	if total == lowest {
+		// a single level, nothing to spread
+		for v := range table {
+			table[v] = v
+		}
+		return table
+	}
+	for v, c := range cumulative {
+		if c >= lowest {
+			table[v] = int(math.Round(float64(c-lowest) / float64(total-lowest) * float64(max)))
+		}
+	}
+	return table
+}
+
+
+// CLAHEOptions configures contrast-limited adaptive histogram equalisation.
+type CLAHEOptions struct {
+	TilesX, TilesY int     // grid of areas equalised separately, 8x8 when 0
+	ClipLimit      float64 // highest bin allowed, as a multiple of the mean bin, 2 when 0
+}
+
+
+// clahe equalises the levels of every tile of the grid, clipping the histogram
+// bins above the limit and spreading the excess over all bins, then
+// interpolates bilinearly between the tables of the four nearest tiles.
+func clahe(levels [][]int, max int, opts CLAHEOptions) [][]int {
+	height := len(levels)
+	width := 0
+	if height > 0 {
+		width = len(levels[0])
+	}
+	tilesX, tilesY := opts.TilesX, opts.TilesY
+	if tilesX <= 0 {
+		tilesX = 8
+	}
+	if tilesY <= 0 {
+		tilesY = 8
+	}
+	if tilesX > width {
+		tilesX = width
+	}
+	if tilesY > height {
+		tilesY = height
+	}
+	limit := opts.ClipLimit
+	if limit <= 0 {
+		limit = 2
+	}
+	out := make([][]int, height)
+	for y := range out {
+		out[y] = make([]int, width)
+	}
+	if width == 0 || height == 0 {
+		return out
+	}
+
+	// the tables of every tile
+	tables := make([][][]int, tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		tables[ty] = make([][]int, tilesX)
+		for tx := 0; tx < tilesX; tx++ {
+			x0, x1 := tx*width/tilesX, (tx+1)*width/tilesX
+			y0, y1 := ty*height/tilesY, (ty+1)*height/tilesY
+			counts := make([]int, max+1)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					counts[levels[y][x]]++
+				}
+			}
+
+			clip := int(limit * float64((x1-x0)*(y1-y0)) / float64(max+1))
+			if clip < 1 {
+				clip = 1
+			}
+			excess := 0
+			for v, c := range counts {
+				if c > clip {
+					excess += c - clip
+					counts[v] = clip
+				}
+			}
+			for v := range counts {
+				counts[v] += excess / len(counts)
+			}
+			// the remainder goes to bins evenly spaced over the range
+			rest := excess % len(counts)
+			for i := 0; i < rest; i++ {
+				counts[i*len(counts)/rest]++
+			}
+
+			table := make([]int, max+1)
+			cumulative := CumulativeHistogram(counts)
+			total := cumulative[max]
+			for v := range table {
+				table[v] = int(math.Round(float64(cumulative[v]) / float64(total) * float64(max)))
+			}
+			tables[ty][tx] = table
+		}
+	}
+
+	// position of a pixel between the centres of the tiles on one axis
+	locate := func(p, size, tiles int) (int, int, float64) {
+		f := (float64(p)+0.5)*float64(tiles)/float64(size) - 0.5
+		i := int(math.Floor(f))
+		t := f - float64(i)
+		if i < 0 {
+			return 0, 0, 0
+		}
+		if i >= tiles-1 {
+			return tiles - 1, tiles - 1, 0
+		}
+		return i, i + 1, t
+	}
+	for y := 0; y < height; y++ {
+		ty0, ty1, fy := locate(y, height, tilesY)
+		for x := 0; x < width; x++ {
+			tx0, tx1, fx := locate(x, width, tilesX)
+			v := levels[y][x]
+			top := (1-fx)*float64(tables[ty0][tx0][v]) + fx*float64(tables[ty0][tx1][v])
+			bottom := (1-fx)*float64(tables[ty1][tx0][v]) + fx*float64(tables[ty1][tx1][v])
+			out[y][x] = int(math.Round((1-fy)*top + fy*bottom))
+		}
+	}
+	return out
+}
+
+
+// levels returns the gray levels of the image as ints
+func (pgm *PGM) levels() [][]int {
+	levels := make([][]int, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		levels[y] = make([]int, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			levels[y][x] = int(pgm.data[y][x])
+		}
+	}
+	return levels
+}
+
+
+// HistogramEqualize spreads the gray levels so that they are used evenly from 0
+// to maxval.
+func (pgm *PGM) HistogramEqualize() {
+	table := equalizeTable(pgm.Histogram(), pgm.max)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = uint8(table[pgm.data[y][x]])
+		}
+	}
+}
+
+
+// CLAHE applies contrast-limited adaptive histogram equalisation, which brings
+// out local details without amplifying noise in flat areas.
+func (pgm *PGM) CLAHE(opts CLAHEOptions) {
+	out := clahe(pgm.levels(), pgm.max, opts)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = uint8(out[y][x])
+		}
+	}
+}
+
+
+// lumaLevels splits the pixmap into its luma, quantised to the maxval, and its
+// YCbCr colours
+func (ppm *PPM) lumaLevels() ([][]int, [][]YCbCr) {
+	colors := ToColorSpace[YCbCr](ppm)
+	levels := make([][]int, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		levels[y] = make([]int, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			levels[y][x] = int(math.Round(colors[y][x].Y * float64(ppm.max)))
+		}
+	}
+	return levels, colors
+}
+
+
+// setLuma replaces the luma of every pixel by the given levels, keeping the
+// chroma of colors
+func (ppm *PPM) setLuma(levels [][]int, colors [][]YCbCr) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			c := colors[y][x]
+			c.Y = float64(levels[y][x]) / float64(ppm.max)
+			ppm.data[y][x] = c.ToPixel(ppm.max)
+		}
+	}
+}
+
+
+// HistogramEqualize equalises the luma of the pixmap, leaving the chroma
+// unchanged.
+func (ppm *PPM) HistogramEqualize() {
+	levels, colors := ppm.lumaLevels()
+	counts := make([]int, ppm.max+1)
+	for _, row := range levels {
+		for _, v := range row {
+			counts[v]++
+		}
+	}
+	table := equalizeTable(counts, ppm.max)
+	for _, row := range levels {
+		for x, v := range row {
+			row[x] = table[v]
+		}
+	}
+	ppm.setLuma(levels, colors)
+}
+
+
+// CLAHE applies contrast-limited adaptive histogram equalisation to the luma of
+// the pixmap, leaving the chroma unchanged.
+func (ppm *PPM) CLAHE(opts CLAHEOptions) {
+	levels, colors := ppm.lumaLevels()
+	ppm.setLuma(clahe(levels, ppm.max, opts), colors)
+}
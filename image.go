@@ -0,0 +1,156 @@
+package Netpbm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Image is implemented by *PBM, *PGM and *PPM.
+type Image interface {
+	Size() (int, int)
+}
+
+
+// format orders the three image types from the poorest to the richest
+type format int
+
+const (
+	formatPBM format = iota
+	formatPGM
+	formatPPM
+)
+
+
+// commonFormat returns the richest format found in images and the largest maxval
+// among them (1 when they are all bitmaps).
+func commonFormat(images []Image) (format, int, error) {
+	if len(images) == 0 {
+		return formatPBM, 0, errors.New("no image given")
+	}
+	f, max := formatPBM, 1
+	for _, img := range images {
+		switch img := img.(type) {
+		case *PBM:
+		case *PGM:
+			if f < formatPGM {
+				f = formatPGM
+			}
+			if img.max > max {
+				max = img.max
+			}
+		case *PPM:
+			f = formatPPM
+			if img.max > max {
+				max = img.max
+			}
+		default:
+			return formatPBM, 0, fmt.Errorf("unsupported image type %T", img)
+		}
+	}
+	return f, max, nil
+}
+
+
+// asPPM promotes any image to a PPM with the given maxval.
+func asPPM(img Image, max int) *PPM {
+	var ppm *PPM
+	switch img := img.(type) {
+	case *PBM:
+		ppm = img.ToPPM()
+	case *PGM:
+		ppm = img.ToPPM()
+	case *PPM:
+		ppm = NewPPM(img.width, img.height, img.max)
+		ppm.paste(img, Point{0, 0})
+	}
+	if ppm.max != max {
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				p := ppm.data[y][x]
+				ppm.data[y][x] = Pixel{rescale(p.R, ppm.max, max), rescale(p.G, ppm.max, max), rescale(p.B, ppm.max, max)}
+			}
+		}
+		ppm.max = max
+	}
+	return ppm
+}
+
+
+// fromPPM brings back a PPM built by asPPM to the format f. Gray images
+// only carry the red channel, bitmaps store 0 or 1.
+func fromPPM(ppm *PPM, f format) Image {
+	switch f {
+	case formatPBM:
+		pbm := NewPBM(ppm.width, ppm.height)
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				pbm.data[y][x] = ppm.data[y][x].R > 0
+			}
+		}
+		return pbm
+	case formatPGM:
+		pgm := NewPGM(ppm.width, ppm.height, ppm.max)
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				pgm.data[y][x] = ppm.data[y][x].R
+			}
+		}
+		return pgm
+	}
+	return ppm
+}
+
+
+// colorFor converts an 8-bit colour to the maxval max and format f, so it can be
+// drawn on a PPM that will later go through fromPPM.
+func colorFor(c Pixel, f format, max int) Pixel {
+	switch f {
+	case formatPBM:
+		if gray(c) > 127 {
+			return Pixel{1, 1, 1}
+		}
+		return Pixel{}
+	case formatPGM:
+		g := rescale(gray(c), 255, max)
+		return Pixel{g, g, g}
+	}
+	return Pixel{rescale(c.R, 255, max), rescale(c.G, 255, max), rescale(c.B, 255, max)}
+}
+
+
+// Rectangle is an area of an image, X and Y being its top-left corner.
+type Rectangle struct {
+	X, Y, Width, Height int
+}
+
+
+// clip returns the part of r lying inside an image of the given size
+func (r Rectangle) clip(width, height int) Rectangle {
+	x0, y0 := r.X, r.Y
+	x1, y1 := r.X+r.Width, r.Y+r.Height
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > width {
+		x1 = width
+	}
+	if y1 > height {
+		y1 = height
+	}
+	if x1 < x0 {
+		x1 = x0
+	}
+	if y1 < y0 {
+		y1 = y0
+	}
+	return Rectangle{x0, y0, x1 - x0, y1 - y0}
+}
+
+
+// contains tells whether pixel (x, y) lies inside r
+func (r Rectangle) contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
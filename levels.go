@@ -0,0 +1,211 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// curveTable builds the lookup table of f for maxval max, f working on values
+// going from 0 to 1
+func curveTable(max int, f func(v float64) float64) []uint8 {
+	table := make([]uint8, max+1)
+	for v := range table {
+		table[v] = sample(f(float64(v)/float64(max)), max)
+	}
+	return table
+}
+
+
+// brightnessContrast returns the curve adding brightness (-1 to 1) and scaling
+// the distance to mid-gray by 1 + contrast (contrast from -1 to +inf)
+func brightnessContrast(brightness, contrast float64) func(float64) float64 {
+	return func(v float64) float64 {
+		return (v-0.5)*(1+contrast) + 0.5 + brightness
+	}
+}
+
+
+// gammaCurve returns the curve v^(1/gamma), gamma above 1 brightening. A gamma
+// of 0 or less, which has no meaning, leaves the levels as they are.
+func gammaCurve(gamma float64) func(float64) float64 {
+	if gamma <= 0 {
+		gamma = 1
+	}
+	return func(v float64) float64 {
+		return math.Pow(v, 1/gamma)
+	}
+}
+
+
+// levelsCurve returns the curve mapping black to 0 and white to 1, both given
+// as fractions of the maxval, with midtone acting as a gamma in between. When
+// white is not above black the curve is a threshold at black, black itself
+// being white.
+func levelsCurve(black, white, midtone float64) func(float64) float64 {
+	gamma := gammaCurve(midtone)
+	return func(v float64) float64 {
+		if white <= black {
+			if v >= black {
+				return 1
+			}
+			return 0
+		}
+		v = math.Min(math.Max((v-black)/(white-black), 0), 1)
+		return gamma(v)
+	}
+}
+
+
+// percentiles returns the levels below which lowFraction of the pixels lie and
+// above which highFraction of them lie
+func percentiles(counts []int, lowFraction, highFraction float64) (int, int) {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	low, high := 0, len(counts)-1
+	seen := 0
+	for v, c := range counts {
+		seen += c
+		if float64(seen) > lowFraction*float64(total) {
+			low = v
+			break
+		}
+	}
+	seen = 0
+	for v := len(counts) - 1; v >= 0; v-- {
+		seen += counts[v]
+		if float64(seen) > highFraction*float64(total) {
+			high = v
+			break
+		}
+	}
+	return low, high
+}
+
+
+// ApplyLUT replaces every gray level v by table[v]. The table must have maxval+1
+// entries, none above the maxval.
+func (pgm *PGM) ApplyLUT(table []uint8) error {
+	if len(table) != pgm.max+1 {
+		return errors.New("lookup table size does not match the maxval")
+	}
+	for _, v := range table {
+		if int(v) > pgm.max {
+			return errors.New("lookup table value above the maxval")
+		}
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = table[pgm.data[y][x]]
+		}
+	}
+	return nil
+}
+
+
+// ApplyCurve maps every gray level through f, which takes and returns values
+// going from 0 (black) to 1 (white). Results are clamped.
+func (pgm *PGM) ApplyCurve(f func(v float64) float64) {
+	pgm.ApplyLUT(curveTable(pgm.max, f))
+}
+
+
+// BrightnessContrast adds brightness (-1 to 1) to every pixel and multiplies its
+// distance to mid-gray by 1 + contrast.
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64) {
+	pgm.ApplyCurve(brightnessContrast(brightness, contrast))
+}
+
+
+// Gamma applies a gamma correction, values above 1 brightening the midtones.
+// A gamma of 0 or less leaves the image as it is.
+func (pgm *PGM) Gamma(gamma float64) {
+	pgm.ApplyCurve(gammaCurve(gamma))
+}
+
+
+// Levels maps the black point to 0 and the white point to maxval, clipping what
+// lies outside, with midtone acting as a gamma correction in between; a
+// midtone of 0 or less counts as 1.
+func (pgm *PGM) Levels(black, white int, midtone float64) {
+	m := float64(pgm.max)
+	pgm.ApplyCurve(levelsCurve(float64(black)/m, float64(white)/m, midtone))
+}
+
+
+// Normalize stretches the gray levels to the full range, like pnmnorm. The
+// darkest lowFraction and brightest highFraction of the pixels are clipped,
+// for instance 0.02 and 0.01. An image whose limits meet, such as a flat one,
+// is left as it is.
+func (pgm *PGM) Normalize(lowFraction, highFraction float64) {
+	low, high := percentiles(pgm.Histogram(), lowFraction, highFraction)
+	if high <= low {
+		return
+	}
+	pgm.Levels(low, high, 1)
+}
+
+
+// ApplyLUT replaces every sample v of every channel by table[v]. The table must
+// have maxval+1 entries, none above the maxval.
+func (ppm *PPM) ApplyLUT(table []uint8) error {
+	if len(table) != ppm.max+1 {
+		return errors.New("lookup table size does not match the maxval")
+	}
+	for _, v := range table {
+		if int(v) > ppm.max {
+			return errors.New("lookup table value above the maxval")
+		}
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			p := ppm.data[y][x]
+			ppm.data[y][x] = Pixel{table[p.R], table[p.G], table[p.B]}
+		}
+	}
+	return nil
+}
+
+
+// ApplyCurve maps every sample through f, which takes and returns values going
+// from 0 to 1. Results are clamped.
+func (ppm *PPM) ApplyCurve(f func(v float64) float64) {
+	ppm.ApplyLUT(curveTable(ppm.max, f))
+}
+
+
+// BrightnessContrast adds brightness (-1 to 1) to every sample and multiplies
+// its distance to mid-gray by 1 + contrast.
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64) {
+	ppm.ApplyCurve(brightnessContrast(brightness, contrast))
+}
+
+
+// Gamma applies a gamma correction, values above 1 brightening the midtones.
+// A gamma of 0 or less leaves the image as it is.
+func (ppm *PPM) Gamma(gamma float64) {
+	ppm.ApplyCurve(gammaCurve(gamma))
+}
+
+
+// Levels maps the black point to 0 and the white point to maxval on every
+// channel, clipping what lies outside, with midtone acting as a gamma
+// correction in between; a midtone of 0 or less counts as 1.
+func (ppm *PPM) Levels(black, white int, midtone float64) {
+	m := float64(ppm.max)
+	ppm.ApplyCurve(levelsCurve(float64(black)/m, float64(white)/m, midtone))
+}
+
+
+// Normalize stretches the pixmap to the full range, like pnmnorm. The limits
+// are found on the luma, the darkest lowFraction and brightest highFraction of
+// the pixels being clipped, and the same stretch is applied to every channel
+// so that hues are kept. An image whose limits meet is left as it is.
+func (ppm *PPM) Normalize(lowFraction, highFraction float64) {
+	low, high := percentiles(ppm.ToPGM().Histogram(), lowFraction, highFraction)
+	if high <= low {
+		return
+	}
+	ppm.Levels(low, high, 1)
+}
@@ -0,0 +1,48 @@
+package Netpbm
+
+import "testing"
+
+func TestNormalizeFlat(t *testing.T) {
+	flat := NewPGM(10, 10, 255)
+	flat.fill(128)
+	flat.Normalize(0.02, 0.01)
+	if flat.data[0][0] != 128 {
+		t.Errorf("a flat image became %d", flat.data[0][0])
+	}
+
+	// a page that is 99% background keeps its background and its speck
+	page := NewPGM(10, 10, 255)
+	page.fill(200)
+	page.data[4][4] = 10
+	page.Normalize(0.02, 0.01)
+	if page.data[0][0] != 200 || page.data[4][4] != 10 {
+		t.Errorf("got background %d and speck %d", page.data[0][0], page.data[4][4])
+	}
+	color := page.ToPPM()
+	color.Normalize(0.02, 0.01)
+	if color.data[0][0] != (Pixel{200, 200, 200}) {
+		t.Errorf("got background %v", color.data[0][0])
+	}
+
+	// equal black and white points threshold at black, which stays white
+	page.Levels(200, 200, 1)
+	if page.data[0][0] != 255 || page.data[4][4] != 0 {
+		t.Errorf("got background %d and speck %d", page.data[0][0], page.data[4][4])
+	}
+}
+
+
+func TestGammaZero(t *testing.T) {
+	for _, gamma := range []float64{0, -2} {
+		pgm := NewPGM(1, 1, 255)
+		pgm.fill(100)
+		pgm.Gamma(gamma)
+		if pgm.data[0][0] != 100 {
+			t.Errorf("gamma %g: got %d, want 100", gamma, pgm.data[0][0])
+		}
+		pgm.Levels(0, 255, gamma)
+		if pgm.data[0][0] != 100 {
+			t.Errorf("midtone %g: got %d, want 100", gamma, pgm.data[0][0])
+		}
+	}
+}
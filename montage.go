@@ -0,0 +1,70 @@
+package Netpbm
+
+import "math"
+
+// MontageOptions describes the contact sheet built by Montage.
+// Colours are 8-bit and converted to the format of the sheet.
+type MontageOptions struct {
+	Columns    int       // images per row, 0 picks a grid as square as possible
+	Spacing    int       // pixels between two cells
+	Margin     int       // pixels around the whole grid
+	Align      Alignment // placement of an image inside its cell, on both axes
+	Background Pixel
+	Labels     []string // optional caption written under each image
+	LabelColor Pixel
+}
+
+
+// Montage lays out images on a grid of equal cells, each as large as the biggest
+// image. Mixed types are promoted to the richest format and the largest maxval,
+// as with Concat.
+func Montage(images []Image, opts MontageOptions) (Image, error) {
+	f, max, err := commonFormat(images)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := opts.Columns
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(images)))))
+	}
+	rows := (len(images) + columns - 1) / columns
+
+	cellWidth, cellHeight := 0, 0
+	for _, img := range images {
+		w, h := img.Size()
+		if w > cellWidth {
+			cellWidth = w
+		}
+		if h > cellHeight {
+			cellHeight = h
+		}
+	}
+	labelHeight := 0
+	if len(opts.Labels) > 0 {
+		labelHeight = glyphHeight + 2
+	}
+
+	width := 2*opts.Margin + columns*cellWidth + (columns-1)*opts.Spacing
+	height := 2*opts.Margin + rows*(cellHeight+labelHeight) + (rows-1)*opts.Spacing
+	sheet := NewPPM(width, height, max)
+	sheet.fill(colorFor(opts.Background, f, max))
+	labelColor := colorFor(opts.LabelColor, f, max)
+
+	for i, img := range images {
+		left := opts.Margin + (i%columns)*(cellWidth+opts.Spacing)
+		top := opts.Margin + (i/columns)*(cellHeight+labelHeight+opts.Spacing)
+		w, h := img.Size()
+		sheet.paste(asPPM(img, max), Point{left + opts.Align.offset(w, cellWidth), top + opts.Align.offset(h, cellHeight)})
+
+		if i < len(opts.Labels) {
+			label := opts.Labels[i]
+			tw := textWidth(label)
+			if tw > cellWidth {
+				tw = cellWidth
+			}
+			sheet.drawText(Point{left + (cellWidth-tw)/2, top + cellHeight + 1}, label, cellWidth, labelColor)
+		}
+	}
+	return fromPPM(sheet, f), nil
+}
@@ -0,0 +1,315 @@
+package Netpbm
+
+// Structuring elements are bitmaps whose true pixels are the neighbourhood of
+// the operation, centred on the pixel (width/2, height/2). A nil element is a
+// 3x3 square.
+
+// RectangleElement returns a structuring element of width x height pixels.
+func RectangleElement(width, height int) *PBM {
+	se := NewPBM(width, height)
+	se.fill(true)
+	return se
+}
+
+
+// DiskElement returns a round structuring element of the given radius, its
+// side being 2*radius+1.
+func DiskElement(radius int) *PBM {
+	se := NewPBM(2*radius+1, 2*radius+1)
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			// the half pixel makes small disks rounder
+			if float64(x*x+y*y) <= (float64(radius)+0.5)*(float64(radius)+0.5) {
+				se.data[y+radius][x+radius] = true
+			}
+		}
+	}
+	return se
+}
+
+
+// CrossElement returns a plus-shaped structuring element whose arms are
+// radius pixels long.
+func CrossElement(radius int) *PBM {
+	se := NewPBM(2*radius+1, 2*radius+1)
+	for i := 0; i <= 2*radius; i++ {
+		se.data[radius][i] = true
+		se.data[i][radius] = true
+	}
+	return se
+}
+
+
+// offsets returns the positions of the true pixels of a structuring element
+// relative to its centre
+func offsets(se *PBM) []Point {
+	if se == nil {
+		se = RectangleElement(3, 3)
+	}
+	points := make([]Point, 0)
+	for y := 0; y < se.height; y++ {
+		for x := 0; x < se.width; x++ {
+			if se.data[y][x] {
+				points = append(points, Point{x - se.width/2, y - se.height/2})
+			}
+		}
+	}
+	return points
+}
+
+
+// morph combines with pick the values under a structuring element, starting
+// from identity. Erosion reads the element as is and dilation reflected
+// (sign -1), so that opening and closing are idempotent. Pixels outside the
+// image are ignored.
+func morph[T any](data [][]T, width, height int, points []Point, sign int, identity T, pick func(T, T) T) [][]T {
+	out := make([][]T, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]T, width)
+		for x := 0; x < width; x++ {
+			v := identity
+			for _, p := range points {
+				sx, sy := x+sign*p.X, y+sign*p.Y
+				if sx >= 0 && sx < width && sy >= 0 && sy < height {
+					v = pick(v, data[sy][sx])
+				}
+			}
+			out[y][x] = v
+		}
+	}
+	return out
+}
+
+
+// and, or, minUint8 and maxUint8 are the combinations of erosion and dilation
+func and(a, b bool) bool {
+	return a && b
+}
+
+func or(a, b bool) bool {
+	return a || b
+}
+
+func minUint8(a, b uint8) uint8 {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+
+// Erode shrinks the true regions of the bitmap: a pixel stays true only when
+// all the pixels under the structuring element are true.
+func (pbm *PBM) Erode(se *PBM) {
+	pbm.data = morph(pbm.data, pbm.width, pbm.height, offsets(se), 1, true, and)
+}
+
+
+// Dilate grows the true regions of the bitmap: a pixel becomes true when any
+// pixel under the reflected structuring element is true.
+func (pbm *PBM) Dilate(se *PBM) {
+	pbm.data = morph(pbm.data, pbm.width, pbm.height, offsets(se), -1, false, or)
+}
+
+
+// Open erodes then dilates the bitmap, removing the true parts smaller than
+// the structuring element.
+func (pbm *PBM) Open(se *PBM) {
+	pbm.Erode(se)
+	pbm.Dilate(se)
+}
+
+
+// Close dilates then erodes the bitmap, filling the false holes and gaps
+// smaller than the structuring element.
+func (pbm *PBM) Close(se *PBM) {
+	pbm.Dilate(se)
+	pbm.Erode(se)
+}
+
+
+// HitOrMiss keeps the pixels where the true pixels of hit all lie on true
+// pixels and the true pixels of miss all lie on false ones, both elements
+// being centred on the pixel. Pixels outside the image count as false. It is
+// the building block of thinning and pattern detection.
+func (pbm *PBM) HitOrMiss(hit, miss *PBM) {
+	hits, misses := offsets(hit), make([]Point, 0)
+	if miss != nil {
+		misses = offsets(miss)
+	}
+	at := func(x, y int) bool {
+		return x >= 0 && x < pbm.width && y >= 0 && y < pbm.height && pbm.data[y][x]
+	}
+	out := make([][]bool, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		out[y] = make([]bool, pbm.width)
+		for x := 0; x < pbm.width; x++ {
+			match := true
+			for _, p := range hits {
+				if !at(x+p.X, y+p.Y) {
+					match = false
+					break
+				}
+			}
+			for _, p := range misses {
+				if !match {
+					break
+				}
+				match = !at(x+p.X, y+p.Y)
+			}
+			out[y][x] = match
+		}
+	}
+	pbm.data = out
+}
+
+
+// TopHat keeps the true parts of the bitmap that an opening with the
+// structuring element removes, that is the details smaller than it.
+func (pbm *PBM) TopHat(se *PBM) {
+	opened := pbm.SubImage(Rectangle{0, 0, pbm.width, pbm.height})
+	opened.Open(se)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = pbm.data[y][x] && !opened.data[y][x]
+		}
+	}
+}
+
+
+// BlackHat keeps the false parts of the bitmap that a closing with the
+// structuring element fills, that is the holes and gaps smaller than it.
+func (pbm *PBM) BlackHat(se *PBM) {
+	closed := pbm.SubImage(Rectangle{0, 0, pbm.width, pbm.height})
+	closed.Close(se)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = closed.data[y][x] && !pbm.data[y][x]
+		}
+	}
+}
+
+
+// MorphGradient keeps the pixels a dilation adds or an erosion removes, which
+// outlines the true regions of the bitmap.
+func (pbm *PBM) MorphGradient(se *PBM) {
+	points := offsets(se)
+	eroded := morph(pbm.data, pbm.width, pbm.height, points, 1, true, and)
+	dilated := morph(pbm.data, pbm.width, pbm.height, points, -1, false, or)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = dilated[y][x] && !eroded[y][x]
+		}
+	}
+}
+
+
+// Erode replaces every pixel of the graymap by the minimum under the
+// structuring element, which darkens and shrinks bright areas.
+func (pgm *PGM) Erode(se *PBM) {
+	pgm.data = morph(pgm.data, pgm.width, pgm.height, offsets(se), 1, uint8(pgm.max), minUint8)
+}
+
+
+// Dilate replaces every pixel of the graymap by the maximum under the
+// reflected structuring element, which brightens and grows bright areas.
+func (pgm *PGM) Dilate(se *PBM) {
+	pgm.data = morph(pgm.data, pgm.width, pgm.height, offsets(se), -1, 0, maxUint8)
+}
+
+
+// Open erodes then dilates the graymap, removing the bright details smaller
+// than the structuring element.
+func (pgm *PGM) Open(se *PBM) {
+	pgm.Erode(se)
+	pgm.Dilate(se)
+}
+
+
+// Close dilates then erodes the graymap, removing the dark details smaller
+// than the structuring element.
+func (pgm *PGM) Close(se *PBM) {
+	pgm.Dilate(se)
+	pgm.Erode(se)
+}
+
+
+// HitOrMiss is the grayscale hit-or-miss transform: every pixel becomes the
+// amount by which the minimum under hit exceeds the maximum under miss, or 0.
+// Pixels outside the image count as 0.
+func (pgm *PGM) HitOrMiss(hit, miss *PBM) {
+	hits, misses := offsets(hit), make([]Point, 0)
+	if miss != nil {
+		misses = offsets(miss)
+	}
+	at := func(x, y int) uint8 {
+		if x < 0 || x >= pgm.width || y < 0 || y >= pgm.height {
+			return 0
+		}
+		return pgm.data[y][x]
+	}
+	out := make([][]uint8, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		out[y] = make([]uint8, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			low, high := uint8(pgm.max), uint8(0)
+			for _, p := range hits {
+				low = minUint8(low, at(x+p.X, y+p.Y))
+			}
+			for _, p := range misses {
+				high = maxUint8(high, at(x+p.X, y+p.Y))
+			}
+			if low > high {
+				out[y][x] = low - high
+			}
+		}
+	}
+	pgm.data = out
+}
+
+
+// TopHat subtracts the opening of the graymap from it, leaving the bright
+// details smaller than the structuring element on a black background.
+func (pgm *PGM) TopHat(se *PBM) {
+	opened := pgm.SubImage(Rectangle{0, 0, pgm.width, pgm.height})
+	opened.Open(se)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] -= opened.data[y][x]
+		}
+	}
+}
+
+
+// BlackHat subtracts the graymap from its closing, leaving the dark details
+// smaller than the structuring element as bright ones on a black background.
+func (pgm *PGM) BlackHat(se *PBM) {
+	closed := pgm.SubImage(Rectangle{0, 0, pgm.width, pgm.height})
+	closed.Close(se)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = closed.data[y][x] - pgm.data[y][x]
+		}
+	}
+}
+
+
+// MorphGradient replaces the graymap by its dilation minus its erosion, which
+// is bright along the edges.
+func (pgm *PGM) MorphGradient(se *PBM) {
+	points := offsets(se)
+	eroded := morph(pgm.data, pgm.width, pgm.height, points, 1, uint8(pgm.max), minUint8)
+	dilated := morph(pgm.data, pgm.width, pgm.height, points, -1, 0, maxUint8)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = dilated[y][x] - eroded[y][x]
+		}
+	}
+}
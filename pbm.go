@@ -13,6 +13,7 @@ type PBM struct{
     data [][]bool
     width, height int
     magicNumber string
+    clip *Rectangle // area drawing is restricted to, the whole image when nil
 }
 
 
@@ -198,3 +199,79 @@ func (pbm *PBM) SetMagicNumber(magicNumber string) {
     fmt.Println(pbm.data[0][0], pbm.data[0][1])
 }
 
+
+
+
+// NewPBM returns a blank PBM image of the given size.
+func NewPBM(width, height int) *PBM {
+	data := make([][]bool, height)
+	for y := range data {
+		data[y] = make([]bool, width)
+	}
+	return &PBM{
+		data:        data,
+		width:       width,
+		height:      height,
+		magicNumber: "P1",
+	}
+}
+
+
+
+// ToPGM converts the bitmap to a PGM with maxval 255, true pixels becoming 255.
+func (pbm *PBM) ToPGM() *PGM {
+	pgm := NewPGM(pbm.width, pbm.height, 255)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				pgm.data[y][x] = 255
+			}
+		}
+	}
+	return pgm
+}
+
+
+
+// ToPPM converts the bitmap to a PPM with maxval 255, true pixels becoming white.
+func (pbm *PBM) ToPPM() *PPM {
+	return pbm.ToPGM().ToPPM()
+}
+
+
+
+// fill sets every pixel to value
+func (pbm *PBM) fill(value bool) {
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = value
+		}
+	}
+}
+
+
+
+// paste copies src into pbm with its top-left corner at the given point,
+// ignoring the parts falling outside
+func (pbm *PBM) paste(src *PBM, at Point) {
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			dx, dy := at.X+x, at.Y+y
+			if dx >= 0 && dx < pbm.width && dy >= 0 && dy < pbm.height {
+				pbm.data[dy][dx] = src.data[y][x]
+			}
+		}
+	}
+}
+
+
+
+// SubImage returns a copy of the area r, clipped to the image.
+func (pbm *PBM) SubImage(r Rectangle) *PBM {
+	r = r.clip(pbm.width, pbm.height)
+	sub := NewPBM(r.Width, r.Height)
+	for y := 0; y < r.Height; y++ {
+		copy(sub.data[y], pbm.data[r.Y+y][r.X:r.X+r.Width])
+	}
+	return sub
+}
@@ -14,6 +14,7 @@ type PGM struct{
     width, height int
     magicNumber string
     max int
+    clip *Rectangle // area drawing is restricted to, the whole image when nil
 }
 
 
@@ -202,14 +203,21 @@ func (pgm *PGM) Rotate90CW(){
 
 
 
-func (pgm *PGM) ToPBM() *PBM {
-	data := make([][]bool, pgm.height)
+// ToPBM converts the graymap to a bitmap, true pixels being the bright ones.
+// An optional DitherOptions selects halftoning instead of the plain threshold.
+func (pgm *PGM) ToPBM(opts ...DitherOptions) *PBM {
+	var o DitherOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	levels := make([][]float64, pgm.height)
 	for y := 0; y < pgm.height; y++ {
-		data[y] = make([]bool, pgm.width)
+		levels[y] = make([]float64, pgm.width)
 		for x := 0; x < pgm.width; x++ {
-			data[y][x] = pgm.data[y][x] > uint8(pgm.max/2)
+			levels[y][x] = float64(pgm.data[y][x]) / float64(pgm.max)
 		}
 	}
+	data := dither(levels, o)
 	return &PBM{
 		data:        data,
 		width:       pgm.width,
@@ -217,3 +225,82 @@ func (pgm *PGM) ToPBM() *PBM {
 		magicNumber: "P4",
 	}
 }
+
+
+
+// NewPGM returns a black PGM image of the given size and maxval.
+func NewPGM(width, height, max int) *PGM {
+	data := make([][]uint8, height)
+	for y := range data {
+		data[y] = make([]uint8, width)
+	}
+	return &PGM{
+		data:        data,
+		width:       width,
+		height:      height,
+		magicNumber: "P2",
+		max:         max,
+	}
+}
+
+
+
+// ToPPM converts the graymap to a PPM with the same maxval.
+func (pgm *PGM) ToPPM() *PPM {
+	ppm := NewPPM(pgm.width, pgm.height, pgm.max)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			v := pgm.data[y][x]
+			ppm.data[y][x] = Pixel{v, v, v}
+		}
+	}
+	return ppm
+}
+
+
+
+// fill sets every pixel to value
+func (pgm *PGM) fill(value uint8) {
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = value
+		}
+	}
+}
+
+
+
+// paste copies src into pgm with its top-left corner at the given point,
+// ignoring the parts falling outside
+func (pgm *PGM) paste(src *PGM, at Point) {
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			dx, dy := at.X+x, at.Y+y
+			if dx >= 0 && dx < pgm.width && dy >= 0 && dy < pgm.height {
+				pgm.data[dy][dx] = src.data[y][x]
+			}
+		}
+	}
+}
+
+
+
+// rescale converts a sample from one maxval to another, rounding to nearest
+func rescale(value uint8, from, to int) uint8 {
+	if from == to || from <= 0 {
+		return value
+	}
+	return uint8((int(value)*to + from/2) / from)
+}
+
+
+
+// SubImage returns a copy of the area r, clipped to the image.
+func (pgm *PGM) SubImage(r Rectangle) *PGM {
+	r = r.clip(pgm.width, pgm.height)
+	sub := NewPGM(r.Width, r.Height, pgm.max)
+	for y := 0; y < r.Height; y++ {
+		copy(sub.data[y], pgm.data[r.Y+y][r.X:r.X+r.Width])
+	}
+	return sub
+}
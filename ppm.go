@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 )
@@ -19,6 +18,7 @@ type PPM struct{
     width, height int
     magicNumber string
     max int
+    clip *Rectangle // area drawing is restricted to, the whole image when nil
 }
 
 
@@ -207,7 +207,85 @@ func (ppm *PPM) Rotate90CW() {
 
 
 
-func (ppm *PPM) ToPGM() *PGM {
+// Channel names one of the three channels of a Pixel.
+type Channel int
+
+const (
+	Red Channel = iota
+	Green
+	Blue
+)
+
+
+// channel returns the value of channel c
+func (p Pixel) channel(c Channel) uint8 {
+	switch c {
+	case Green:
+		return p.G
+	case Blue:
+		return p.B
+	}
+	return p.R
+}
+
+
+
+// GrayMethod tells how ToPGM turns a colour into a gray level.
+type GrayMethod int
+
+const (
+	GrayBT601     GrayMethod = iota // 0.299 R + 0.587 G + 0.114 B on the encoded samples
+	GrayBT709                       // 0.2126 R + 0.7152 G + 0.0722 B on the encoded samples
+	GrayLuminance                   // BT.709 weights on linear light, encoded back to sRGB
+	GrayAverage                     // (R + G + B) / 3
+	GrayLightness                   // (max + min) / 2 of the channels
+	GrayChannel                     // a single channel, see GrayOptions.Channel
+	GrayCustom                      // GrayOptions.Weights
+)
+
+
+// GrayOptions configures ToPGM. The zero value gives BT.601 luma.
+type GrayOptions struct {
+	Method  GrayMethod
+	Channel Channel    // channel kept by GrayChannel
+	Weights [3]float64 // R, G and B weights of GrayCustom, divided by their sum
+}
+
+
+// level returns the gray level of p, from 0 to 1, for an image of maxval max
+func (opts GrayOptions) level(p Pixel, max int) float64 {
+	r, g, b := p.normalize(max)
+	switch opts.Method {
+	case GrayBT709:
+		return 0.2126*r + 0.7152*g + 0.0722*b
+	case GrayLuminance:
+		return delinearize(0.2126*linearize(r) + 0.7152*linearize(g) + 0.0722*linearize(b))
+	case GrayAverage:
+		return (r + g + b) / 3
+	case GrayLightness:
+		return (math.Max(r, math.Max(g, b)) + math.Min(r, math.Min(g, b))) / 2
+	case GrayChannel:
+		return float64(p.channel(opts.Channel)) / float64(max)
+	case GrayCustom:
+		w := opts.Weights
+		sum := w[0] + w[1] + w[2]
+		if sum == 0 {
+			return 0
+		}
+		return (w[0]*r + w[1]*g + w[2]*b) / sum
+	}
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+
+
+// ToPGM converts the image to a PGM with the same maxval. An optional
+// GrayOptions selects the conversion, BT.601 luma being the default.
+func (ppm *PPM) ToPGM(opts ...GrayOptions) *PGM {
+	var o GrayOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
 	pgm := &PGM{
 		data:        make([][]uint8, ppm.height),
 		width:       ppm.width,
@@ -219,8 +297,7 @@ func (ppm *PPM) ToPGM() *PGM {
 	for i := 0; i < ppm.height; i++ {
 		pgm.data[i] = make([]uint8, ppm.width)
 		for j := 0; j < ppm.width; j++ {
-			a := uint8(0.299*float64(ppm.data[i][j].R) + 0.587*float64(ppm.data[i][j].G) + 0.114*float64(ppm.data[i][j].B))
-			pgm.data[i][j] = a
+			pgm.data[i][j] = sample(o.level(ppm.data[i][j], ppm.max), ppm.max)
 		}
 	}
 	return pgm
@@ -229,22 +306,28 @@ func (ppm *PPM) ToPGM() *PGM {
 
 
 
-func (ppm *PPM) ToPBM() *PBM {
-	pbm := &PBM{
-		data:        make([][]bool, ppm.height),
-		width:       ppm.width,
-		height:      ppm.height,
-		magicNumber: "P1",
+// ToPBM converts the pixmap to a bitmap, true pixels being the bright ones.
+// An optional DitherOptions selects halftoning instead of the plain threshold,
+// and the gray conversion done first.
+func (ppm *PPM) ToPBM(opts ...DitherOptions) *PBM {
+	var o DitherOptions
+	if len(opts) > 0 {
+		o = opts[0]
 	}
-
+	levels := make([][]float64, ppm.height)
 	for i := 0; i < ppm.height; i++ {
-		pbm.data[i] = make([]bool, ppm.width)
+		levels[i] = make([]float64, ppm.width)
 		for j := 0; j < ppm.width; j++ {
-			a := 0.299*float64(ppm.data[i][j].R) + 0.587*float64(ppm.data[i][j].G) + 0.114*float64(ppm.data[i][j].B)
-			pbm.data[i][j] = a > float64(ppm.max)/2
+			levels[i][j] = o.Gray.level(ppm.data[i][j], ppm.max)
 		}
 	}
-	return pbm
+
+	return &PBM{
+		data:        dither(levels, o),
+		width:       ppm.width,
+		height:      ppm.height,
+		magicNumber: "P1",
+	}
 }
 
 
@@ -256,250 +339,92 @@ type Point struct{
 
 
 
-func (ppm *PPM) DrawLine(p1, p2 Point, color Pixel) {
-	dx := float64(p2.X - p1.X)
-	dy := float64(p2.Y - p1.Y)
-	steps := int(math.Max(math.Abs(dx), math.Abs(dy)))
-
-	xi := dx / float64(steps)
-	yi := dy / float64(steps)
-
-	x, y := float64(p1.X), float64(p1.Y)
-
-	for i := 0; i <= steps; i++ {
-		ppm.Set(int(x), int(y), color)
-		x += xi
-		y += yi
-	}
+// DrawLine draws a line from p1 to p2, one pixel wide unless opts sets the
+// stroke width. Anti-aliased lines of one pixel use Xiaolin Wu's algorithm.
+// Pixels outside the image are skipped.
+func (ppm *PPM) DrawLine(p1, p2 Point, color Pixel, opts ...StrokeOptions) {
+	stroke(ppm.plotter(color), []Point{p1, p2}, false, strokeOptions(opts))
 }
 
 
-func (ppm *PPM) DrawRectangle(p1 Point, width, height int, color Pixel) {
-	p2 := Point{p1.X + width, p1.Y}
-	p3 := Point{p1.X, p1.Y + height}
-	p4 := Point{p1.X + width, p1.Y + height}
-
-	ppm.DrawLine(p1, p2, color)
-	ppm.DrawLine(p2, p4, color)
-	ppm.DrawLine(p4, p3, color)
-	ppm.DrawLine(p3, p1, color)
+// DrawRectangle draws the outline of a rectangle, from p1 to
+// p1 + (width, height).
+func (ppm *PPM) DrawRectangle(p1 Point, width, height int, color Pixel, opts ...StrokeOptions) {
+	stroke(ppm.plotter(color), rectangleCorners(p1, width, height), true, strokeOptions(opts))
 }
 
 
 
+// DrawFilledRectangle fills width x height pixels from p1.
 func (ppm *PPM) DrawFilledRectangle(p1 Point, width, height int, color Pixel) {
-    for i := p1.Y; i < p1.Y+height; i++ {
-        for j := p1.X; j < p1.X+width; j++ {
-            ppm.Set(j, i, color)
-        }
-    }
+	fillRectangle(ppm.plotter(color), p1, width, height)
 }
 
 
 
+// DrawCircle draws a disk of the given radius around center.
 func (ppm *PPM) DrawCircle(center Point, radius int, color Pixel) {
-	for i := 0; i <= radius*2; i++ {
-		for j := 0; j <= radius*2; j++ {
-			x := i - radius
-			y := j - radius
+	fillCircle(ppm.plotter(color), center, radius, radius*radius)
+}
 
-			if x*x+y*y <= radius*radius {
-				ppm.Set(center.X+x, center.Y+y, color)
-			}
-		}
-	}
+
+// DrawCircleOutline draws the outline of a circle of the given radius around
+// center, one pixel wide unless opts sets the stroke width. Thick outlines
+// straddle the radius.
+func (ppm *PPM) DrawCircleOutline(center Point, radius int, color Pixel, opts ...StrokeOptions) {
+	strokeCircle(ppm.plotter(color), center, radius, strokeOptions(opts))
 }
 
 
 
 
+// DrawFilledCircle fills the pixels less than radius+1 away from center.
 func (ppm *PPM) DrawFilledCircle(center Point, radius int, color Pixel) {
-    for y := 0; y < ppm.height; y++ {
-        for x := 0; x < ppm.width; x++ {
-            dx := float64(x - center.X)
-            dy := float64(y - center.Y)
-            distance := math.Sqrt(dx*dx + dy*dy)
-
-            if int(distance) <= radius {
-                ppm.data[y][x] = color
-            }
-        }
-    }
+	fillCircle(ppm.plotter(color), center, radius, (radius+1)*(radius+1)-1)
 }
 
 
 
-func (ppm *PPM) DrawTriangle(p1, p2, p3 Point, color Pixel) {
-	ppm.DrawLine(p1, p2, color)
-	ppm.DrawLine(p2, p3, color)
-	ppm.DrawLine(p3, p1, color)
+// DrawTriangle draws the outline of a triangle.
+func (ppm *PPM) DrawTriangle(p1, p2, p3 Point, color Pixel, opts ...StrokeOptions) {
+	stroke(ppm.plotter(color), []Point{p1, p2, p3}, true, strokeOptions(opts))
 }
 
 
+// DrawFilledTriangle fills a triangle.
 func (ppm *PPM) DrawFilledTriangle(p1, p2, p3 Point, color Pixel) {
-    // Sort the points by Y coordinate
-    var points [3]Point
-    if p1.Y > p2.Y {
-        p1, p2 = p2, p1
-    }
-    if p1.Y > p3.Y {
-        p1, p3 = p3, p1
-    }
-    if p2.Y > p3.Y {
-        p2, p3 = p3, p2
-    }
-
-    // Calculate slopes for the two edges of the triangle
-    slope1 := float64(p2.X-p1.X) / float64(p2.Y-p1.Y)
-    slope2 := float64(p3.X-p1.X) / float64(p3.Y-p1.Y)
-
-    // Initialize starting and ending X coordinates for each row
-    x1 := float64(p1.X)
-    x2 := float64(p1.X)
-
-    // Process each scanline
-    for y := p1.Y; y <= p3.Y; y++ {
-        // Draw the current row
-        for x := int(x1); x <= int(x2); x++ {
-            ppm.data[y][x] = color
-        }
-
-        // Update starting and ending X coordinates for the next row
-        x1 += slope1
-        x2 += slope2
-    }
+	fillPolygon(ppm.plotter(color), [][]Point{{p1, p2, p3}}, FillEvenOdd)
 }
 
 
-func (ppm *PPM) DrawPolygon(points []Point, color Pixel) {
-	for i := 0; i < len(points)-1; i++ {
-		ppm.DrawLine(points[i], points[i+1], color)
-	}
-	ppm.DrawLine(points[len(points)-1], points[0], color)
+// DrawPolygon draws the outline of a polygon, its last point being joined to
+// the first.
+func (ppm *PPM) DrawPolygon(points []Point, color Pixel, opts ...StrokeOptions) {
+	stroke(ppm.plotter(color), points, true, strokeOptions(opts))
 }
 
 
-func (ppm *PPM) DrawFilledPolygon(points []Point, color Pixel) {
-    // Check if there are enough points to form a polygon
-    if len(points) < 3 {
-        return
-    }
-    // Create a list to store the edges of the polygon
-    edges := make([]Point, 0)
-    minY, maxY := points[0].Y, points[0].Y
-    for _, point := range points {
-        if point.Y < minY {
-            minY = point.Y
-        }
-        if point.Y > maxY {
-            maxY = point.Y
-        }
-    }
-    // Populate the edges list with the edges of the polygon
-    for i, point := range points {
-        nextIndex := (i + 1) % len(points)
-        nextPoint := points[nextIndex]
-
-        // Add only the edges that are not horizontal
-        if point.Y != nextPoint.Y {
-            edges = append(edges, point)
-        }
-    }
-
-    // Sort the edges list based on the X-coordinate
-    sort.Slice(edges, func(i, j int) bool {
-        return edges[i].X < edges[j].X
-    })
-
-    // Create a scanline buffer to keep track of the active edges
-    activeEdges := make(map[int]int)
-
-    // Loop through each scanline in the bounding box of the polygon
-    for scanlineY := minY; scanlineY <= maxY; scanlineY++ {
-        // Update the active edges list by removing edges that end at the current scanline
-        for x, endY := range activeEdges {
-            if endY <= scanlineY {
-                delete(activeEdges, x)
-            }
-        }
-
-        // Add new edges to the active edges list
-        for _, edge := range edges {
-            nextIndex := (i + 1) % len(points)
-            nextPoint := points[nextIndex]
-
-            if edge.Y <= scanlineY && nextPoint.Y > scanlineY || nextPoint.Y <= scanlineY && edge.Y > scanlineY {
-                // Calculate the intersection point with the scanline
-                xIntersection := int(float64(edge.X) + float64(scanlineY-edge.Y)/float64(nextPoint.Y-edge.Y)*(float64(nextPoint.X)-float64(edge.X)))
-
-                // Add the intersection point to the active edges list
-                activeEdges[xIntersection] = nextPoint.Y
-            }
-        }
-
-        // Fill the pixels between pairs of active edges
-        sortedX := make([]int, 0, len(activeEdges))
-        for x := range activeEdges {
-            sortedX = append(sortedX, x)
-        }
-        sort.Ints(sortedX)
-
-        for i := 0; i < len(sortedX); i += 2 {
-            startX, endX := sortedX[i], sortedX[i+1]
-
-            // Ensure startX is within bounds
-            if startX < 0 {
-                startX = 0
-            }
+// DrawFilledPolygon fills a polygon, which may be concave or cross itself. The
+// parts it goes around twice are holes with the even-odd rule, the default,
+// and filled with the non-zero rule.
+func (ppm *PPM) DrawFilledPolygon(points []Point, color Pixel, rule ...FillRule) {
+	fillPolygon(ppm.plotter(color), [][]Point{points}, fillRule(rule))
+}
 
-            // Ensure endX is within bounds
-            if endX >= ppm.width {
-                endX = ppm.width - 1
-            }
 
-            // Fill the pixels between startX and endX on the current scanline
-            for x := startX; x <= endX; x++ {
-                ppm.data[scanlineY][x] = color
-            }
-        }
-    }
+// DrawFilledContours fills a polygon made of several closed contours, such as
+// a shape with holes. With the even-odd rule, the default, any contour inside
+// another is a hole; with the non-zero rule holes must go round the other way.
+func (ppm *PPM) DrawFilledContours(contours [][]Point, color Pixel, rule ...FillRule) {
+	fillPolygon(ppm.plotter(color), contours, fillRule(rule))
 }
 
 
 
-// DrawSierpinskiTriangle dessine un triangle de Sierpinski.
+// DrawSierpinskiTriangle draws a Sierpinski triangle of n levels, its base
+// going from start to start + (width, 0) and its top above.
 func (ppm *PPM) DrawSierpinskiTriangle(n int, start Point, width int, color Pixel) {
-	if n == 0 {
-		// Cas de base : dessiner un triangle simple
-		ppm.DrawFilledTriangle(
-			start,
-			Point{start.X + width, start.Y},
-			Point{start.X + width/2, start.Y - int(float64(width)*math.Sqrt(3)/2)},
-			color,
-		)
-	} else {
-		// Limitez la largeur à une valeur raisonnable
-		if width > ppm.width {
-			width = ppm.width
-		}
-
-		// Calculer les points pour les trois triangles récursifs
-		p1 := start
-		p2 := Point{start.X + width, start.Y}
-		p3 := Point{start.X + width/2, start.Y - int(float64(width)*math.Sqrt(3)/2)}
-
-		// Dessiner le triangle central
-		ppm.DrawSierpinskiTriangle(n-1, Point{(p1.X + p2.X) / 2, (p1.Y + p2.Y) / 2}, width/2, color)
-
-		// Dessiner le triangle supérieur
-		ppm.DrawSierpinskiTriangle(n-1, p1, width/2, color)
-
-		// Dessiner le triangle supérieur droit
-		ppm.DrawSierpinskiTriangle(n-1, Point{(p2.X + p3.X) / 2, (p2.Y + p3.Y) / 2}, width/2, color)
-
-		// Dessiner le triangle supérieur gauche
-		ppm.DrawSierpinskiTriangle(n-1, Point{(p1.X + p3.X) / 2, (p1.Y + p3.Y) / 2}, width/2, color)
-	}
+	sierpinski(ppm.plotter(color), n, start, width)
 }
 
 
@@ -576,3 +501,66 @@ func (ppm *PPM) KNearestNeighbors(newWidth, newHeight, k int) {
     ppm.width = newWidth
     ppm.height = newHeight
 }
+
+
+
+// NewPPM returns a black PPM image of the given size and maxval.
+func NewPPM(width, height, max int) *PPM {
+	data := make([][]Pixel, height)
+	for y := range data {
+		data[y] = make([]Pixel, width)
+	}
+	return &PPM{
+		data:        data,
+		width:       width,
+		height:      height,
+		magicNumber: "P3",
+		max:         max,
+	}
+}
+
+
+
+// fill sets every pixel to value
+func (ppm *PPM) fill(value Pixel) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = value
+		}
+	}
+}
+
+
+
+// paste copies src into ppm with its top-left corner at the given point,
+// ignoring the parts falling outside
+func (ppm *PPM) paste(src *PPM, at Point) {
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			dx, dy := at.X+x, at.Y+y
+			if dx >= 0 && dx < ppm.width && dy >= 0 && dy < ppm.height {
+				ppm.data[dy][dx] = src.data[y][x]
+			}
+		}
+	}
+}
+
+
+
+// gray returns the luma of a pixel with the weights used by ToPGM, rounded so
+// that gray pixels keep their value
+func gray(p Pixel) uint8 {
+	return uint8(math.Round(0.299*float64(p.R) + 0.587*float64(p.G) + 0.114*float64(p.B)))
+}
+
+
+
+// SubImage returns a copy of the area r, clipped to the image.
+func (ppm *PPM) SubImage(r Rectangle) *PPM {
+	r = r.clip(ppm.width, ppm.height)
+	sub := NewPPM(r.Width, r.Height, ppm.max)
+	for y := 0; y < r.Height; y++ {
+		copy(sub.data[y], ppm.data[r.Y+y][r.X:r.X+r.Width])
+	}
+	return sub
+}
@@ -0,0 +1,368 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// QuantizeMethod selects how Quantize builds the palette.
+type QuantizeMethod int
+
+const (
+	QuantizeMedianCut QuantizeMethod = iota // splits the colour cube at the median of its widest side
+	QuantizeKMeans                          // refines the median cut palette with k-means
+	QuantizeOctree                          // merges the leaves of an octree of the colours
+)
+
+
+// QuantizeOptions configures Quantize.
+type QuantizeOptions struct {
+	Method     QuantizeMethod
+	Dither     bool // Floyd-Steinberg error diffusion when remapping
+	Iterations int  // k-means iterations, 10 when 0
+}
+
+
+// colorCount is a colour of the image and how many pixels have it
+type colorCount struct {
+	color Pixel
+	count int
+}
+
+
+// colors returns the distinct colours of the image
+func (ppm *PPM) colors() []colorCount {
+	counts := make(map[Pixel]int)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			counts[ppm.data[y][x]]++
+		}
+	}
+	colors := make([]colorCount, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, colorCount{c, n})
+	}
+	// map order is random, keep the results reproducible
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].color, colors[j].color
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+	return colors
+}
+
+
+// average returns the mean colour of colors, weighted by their counts
+func average(colors []colorCount) Pixel {
+	var r, g, b, n float64
+	for _, c := range colors {
+		w := float64(c.count)
+		r += w * float64(c.color.R)
+		g += w * float64(c.color.G)
+		b += w * float64(c.color.B)
+		n += w
+	}
+	if n == 0 {
+		return Pixel{}
+	}
+	return Pixel{uint8(math.Round(r / n)), uint8(math.Round(g / n)), uint8(math.Round(b / n))}
+}
+
+
+// medianCut splits the colours into at most n boxes and returns their means
+func medianCut(colors []colorCount, n int) []Pixel {
+	boxes := [][]colorCount{colors}
+	for len(boxes) < n {
+		// split the box with the widest channel range
+		best, bestChannel, bestRange := -1, Red, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for _, c := range []Channel{Red, Green, Blue} {
+				lo, hi := 255, 0
+				for _, cc := range box {
+					v := int(cc.color.channel(c))
+					if v < lo {
+						lo = v
+					}
+					if v > hi {
+						hi = v
+					}
+				}
+				if hi-lo > bestRange {
+					best, bestChannel, bestRange = i, c, hi-lo
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.SliceStable(box, func(i, j int) bool {
+			return box[i].color.channel(bestChannel) < box[j].color.channel(bestChannel)
+		})
+		total := 0
+		for _, cc := range box {
+			total += cc.count
+		}
+		split, seen := 1, 0
+		for i, cc := range box {
+			seen += cc.count
+			if seen*2 >= total {
+				split = i + 1
+				break
+			}
+		}
+		if split >= len(box) {
+			split = len(box) - 1
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make([]Pixel, len(boxes))
+	for i, box := range boxes {
+		palette[i] = average(box)
+	}
+	return palette
+}
+
+
+// colorDistance returns the squared distance between two colours
+func colorDistance(a, b Pixel) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+
+// nearest returns the index of the palette colour closest to c
+func nearest(palette []Pixel, c Pixel) int {
+	best, bestDistance := 0, math.MaxInt
+	for i, p := range palette {
+		if d := colorDistance(p, c); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+
+// kMeans moves every palette colour to the mean of the colours closest to it,
+// iterations times or until nothing changes
+func kMeans(colors []colorCount, palette []Pixel, iterations int) []Pixel {
+	for it := 0; it < iterations; it++ {
+		clusters := make([][]colorCount, len(palette))
+		for _, c := range colors {
+			i := nearest(palette, c.color)
+			clusters[i] = append(clusters[i], c)
+		}
+		changed := false
+		for i, cluster := range clusters {
+			if len(cluster) == 0 {
+				continue
+			}
+			if m := average(cluster); m != palette[i] {
+				palette[i] = m
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return palette
+}
+
+
+// octreeNode is a node of the colour octree. Nodes holding pixels, the leaves
+// at first and then the nodes their children are folded into, are the colours
+// of the palette.
+type octreeNode struct {
+	children [8]*octreeNode
+	count    int
+	r, g, b  int
+}
+
+
+// octree reduces colours to at most n by building an octree of depth 8 and
+// folding the children of its deepest nodes into them, one at a time
+func octree(colors []colorCount, n int) []Pixel {
+	root := &octreeNode{}
+	levels := make([][]*octreeNode, 8)
+	leaves := 0
+	for _, c := range colors {
+		node := root
+		for depth := 0; depth < 8; depth++ {
+			shift := 7 - depth
+			i := int(c.color.R>>shift&1)<<2 | int(c.color.G>>shift&1)<<1 | int(c.color.B>>shift&1)
+			if node.children[i] == nil {
+				child := &octreeNode{}
+				node.children[i] = child
+				if depth < 7 {
+					levels[depth+1] = append(levels[depth+1], child)
+				}
+			}
+			node = node.children[i]
+		}
+		if node.count == 0 {
+			leaves++
+		}
+		node.count += c.count
+		node.r += c.count * int(c.color.R)
+		node.g += c.count * int(c.color.G)
+		node.b += c.count * int(c.color.B)
+	}
+	levels[0] = []*octreeNode{root}
+
+	// deepest nodes first, and among them the ones with the fewest pixels. When
+	// a level is reached its deeper levels are fully folded, so the children of
+	// its nodes are all leaves. Every fold into a node that already holds
+	// pixels removes one colour, so the count stops exactly at n.
+	for depth := 7; depth >= 0 && leaves > n; depth-- {
+		nodes := levels[depth]
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return subtreeCount(nodes[i]) < subtreeCount(nodes[j])
+		})
+		for _, node := range nodes {
+			children := make([]int, 0, 8)
+			for i, child := range node.children {
+				if child != nil {
+					children = append(children, i)
+				}
+			}
+			sort.SliceStable(children, func(i, j int) bool {
+				return node.children[children[i]].count < node.children[children[j]].count
+			})
+			for _, i := range children {
+				if leaves <= n {
+					break
+				}
+				child := node.children[i]
+				if node.count > 0 {
+					leaves--
+				}
+				node.count += child.count
+				node.r += child.r
+				node.g += child.g
+				node.b += child.b
+				node.children[i] = nil
+			}
+			if leaves <= n {
+				break
+			}
+		}
+	}
+
+	palette := make([]Pixel, 0, leaves)
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.count > 0 {
+			c := float64(node.count)
+			palette = append(palette, Pixel{uint8(math.Round(float64(node.r) / c)), uint8(math.Round(float64(node.g) / c)), uint8(math.Round(float64(node.b) / c))})
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return palette
+}
+
+
+// subtreeCount returns the number of pixels below a node
+func subtreeCount(node *octreeNode) int {
+	total := node.count
+	for _, child := range node.children {
+		if child != nil {
+			total += subtreeCount(child)
+		}
+	}
+	return total
+}
+
+
+// Quantize reduces the image to at most n colours. It returns the remapped
+// image and the palette, whose colours use the maxval of the image.
+func (ppm *PPM) Quantize(n int, opts QuantizeOptions) (*PPM, []Pixel, error) {
+	if n <= 0 {
+		return nil, nil, errors.New("invalid number of colours")
+	}
+	colors := ppm.colors()
+	var palette []Pixel
+	switch opts.Method {
+	case QuantizeOctree:
+		palette = octree(colors, n)
+	case QuantizeKMeans:
+		iterations := opts.Iterations
+		if iterations <= 0 {
+			iterations = 10
+		}
+		palette = kMeans(colors, medianCut(colors, n), iterations)
+	default:
+		palette = medianCut(colors, n)
+	}
+	return ppm.Remap(palette, opts.Dither), palette, nil
+}
+
+
+// Remap returns a copy of the image where every pixel is replaced by the
+// closest colour of the palette, like pnmremap. With dither, the error made on
+// each pixel is spread to its neighbours (Floyd-Steinberg).
+func (ppm *PPM) Remap(palette []Pixel, dither bool) *PPM {
+	out := NewPPM(ppm.width, ppm.height, ppm.max)
+	out.magicNumber = ppm.magicNumber
+	if len(palette) == 0 {
+		return out
+	}
+	if !dither {
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				out.data[y][x] = palette[nearest(palette, ppm.data[y][x])]
+			}
+		}
+		return out
+	}
+
+	values := make([][][3]float64, ppm.height)
+	for y := range values {
+		values[y] = make([][3]float64, ppm.width)
+		for x, p := range ppm.data[y] {
+			values[y][x] = [3]float64{float64(p.R), float64(p.G), float64(p.B)}
+		}
+	}
+	kernel := diffusionKernels[DitherFloydSteinberg]
+	m := float64(ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			v := values[y][x]
+			want := Pixel{
+				uint8(math.Round(math.Min(math.Max(v[0], 0), m))),
+				uint8(math.Round(math.Min(math.Max(v[1], 0), m))),
+				uint8(math.Round(math.Min(math.Max(v[2], 0), m))),
+			}
+			got := palette[nearest(palette, want)]
+			out.data[y][x] = got
+			e := [3]float64{v[0] - float64(got.R), v[1] - float64(got.G), v[2] - float64(got.B)}
+			for _, d := range kernel.spread {
+				nx, ny := x+d.dx, y+d.dy
+				if nx >= 0 && nx < ppm.width && ny < ppm.height {
+					w := float64(d.weight) / float64(kernel.divisor)
+					for c := range e {
+						values[ny][nx][c] += e[c] * w
+					}
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package Netpbm
+
+import "testing"
+
+func TestOctreePaletteSize(t *testing.T) {
+	ppm := NewPPM(64, 64, 255)
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			ppm.data[y][x] = Pixel{uint8(x * 4), uint8(y * 4), uint8((x*y + 37*x) % 256)}
+		}
+	}
+	for _, n := range []int{1, 2, 4, 7, 32, 256} {
+		_, palette, err := ppm.Quantize(n, QuantizeOptions{Method: QuantizeOctree})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(palette) != n {
+			t.Errorf("n=%d: got %d colours", n, len(palette))
+		}
+	}
+
+	// fewer distinct colours than asked for are all kept
+	few := NewPPM(2, 2, 255)
+	few.data[0][0] = Pixel{255, 0, 0}
+	few.data[1][1] = Pixel{0, 0, 255}
+	if _, palette, _ := few.Quantize(8, QuantizeOptions{Method: QuantizeOctree}); len(palette) != 3 {
+		t.Errorf("got %v, want the 3 colours of the image", palette)
+	}
+}
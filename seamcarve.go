@@ -0,0 +1,315 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// SeamCarveOptions holds the optional masks used by SeamCarve. Both must have
+// the size of the image when given.
+type SeamCarveOptions struct {
+	Protect *PBM // true pixels are avoided by the seams
+	Remove  *PBM // true pixels are taken by the seams first
+}
+
+
+// maskEnergy is added to or removed from the energy of masked pixels. It is
+// large enough to outweigh any gradient summed along a seam.
+const maskEnergy = 1e9
+
+
+// seamImage is the state of a carving: pixels, the energy bias coming from the
+// masks, and how to compare and average two pixels.
+type seamImage[T any] struct {
+	data [][]T
+	bias [][]float64
+	diff func(a, b T) float64
+	mix  func(a, b T) T
+}
+
+
+func newSeamImage[T any](data [][]T, opts SeamCarveOptions, diff func(a, b T) float64, mix func(a, b T) T) (*seamImage[T], error) {
+	height := len(data)
+	width := 0
+	if height > 0 {
+		width = len(data[0])
+	}
+	s := &seamImage[T]{
+		data: make([][]T, height),
+		bias: make([][]float64, height),
+		diff: diff,
+		mix:  mix,
+	}
+	for y := range data {
+		s.data[y] = append([]T(nil), data[y]...)
+		s.bias[y] = make([]float64, width)
+	}
+	for _, m := range []struct {
+		mask   *PBM
+		energy float64
+	}{{opts.Protect, maskEnergy}, {opts.Remove, -maskEnergy}} {
+		if m.mask == nil {
+			continue
+		}
+		if m.mask.width != width || m.mask.height != height {
+			return nil, errors.New("mask size does not match the image")
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if m.mask.data[y][x] {
+					s.bias[y][x] += m.energy
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+
+func (s *seamImage[T]) width() int {
+	if len(s.data) == 0 {
+		return 0
+	}
+	return len(s.data[0])
+}
+
+
+// energy returns the gradient magnitude of every pixel plus the mask bias
+func (s *seamImage[T]) energy() [][]float64 {
+	height, width := len(s.data), s.width()
+	e := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		e[y] = make([]float64, width)
+		up, down := y-1, y+1
+		if up < 0 {
+			up = 0
+		}
+		if down >= height {
+			down = height - 1
+		}
+		for x := 0; x < width; x++ {
+			left, right := x-1, x+1
+			if left < 0 {
+				left = 0
+			}
+			if right >= width {
+				right = width - 1
+			}
+			e[y][x] = s.diff(s.data[y][left], s.data[y][right]) + s.diff(s.data[up][x], s.data[down][x]) + s.bias[y][x]
+		}
+	}
+	return e
+}
+
+
+// findSeam returns the column of the lowest energy vertical seam on every row
+func (s *seamImage[T]) findSeam() []int {
+	height, width := len(s.data), s.width()
+	cost := s.energy()
+	for y := 1; y < height; y++ {
+		for x := 0; x < width; x++ {
+			best := cost[y-1][x]
+			if x > 0 && cost[y-1][x-1] < best {
+				best = cost[y-1][x-1]
+			}
+			if x < width-1 && cost[y-1][x+1] < best {
+				best = cost[y-1][x+1]
+			}
+			cost[y][x] += best
+		}
+	}
+
+	seam := make([]int, height)
+	for x := 1; x < width; x++ {
+		if cost[height-1][x] < cost[height-1][seam[height-1]] {
+			seam[height-1] = x
+		}
+	}
+	for y := height - 2; y >= 0; y-- {
+		x := seam[y+1]
+		seam[y] = x
+		for _, nx := range []int{x - 1, x + 1} {
+			if nx >= 0 && nx < width && cost[y][nx] < cost[y][seam[y]] {
+				seam[y] = nx
+			}
+		}
+	}
+	return seam
+}
+
+
+// removeSeam deletes column seam[y] from every row y
+func removeSeam[E any](rows [][]E, seam []int) {
+	for y, x := range seam {
+		rows[y] = append(rows[y][:x], rows[y][x+1:]...)
+	}
+}
+
+
+// transpose swaps rows and columns, so horizontal seams can be handled as
+// vertical ones
+func transpose[E any](rows [][]E) [][]E {
+	if len(rows) == 0 {
+		return rows
+	}
+	t := make([][]E, len(rows[0]))
+	for x := range t {
+		t[x] = make([]E, len(rows))
+		for y := range rows {
+			t[x][y] = rows[y][x]
+		}
+	}
+	return t
+}
+
+
+func (s *seamImage[T]) transpose() {
+	s.data = transpose(s.data)
+	s.bias = transpose(s.bias)
+}
+
+
+// shrink removes n vertical seams
+func (s *seamImage[T]) shrink(n int) {
+	for i := 0; i < n; i++ {
+		seam := s.findSeam()
+		removeSeam(s.data, seam)
+		removeSeam(s.bias, seam)
+	}
+}
+
+
+// grow inserts n vertical seams. The seams to duplicate are found by removing
+// them from a copy, at most half the width at a time so the same seam is not
+// picked over and over; each one is doubled by averaging it with its right
+// neighbour.
+func (s *seamImage[T]) grow(n int) {
+	for n > 0 {
+		height, width := len(s.data), s.width()
+		batch := n
+		if batch > width/2 {
+			batch = int(math.Max(1, float64(width/2)))
+		}
+
+		work := &seamImage[T]{data: make([][]T, height), bias: make([][]float64, height), diff: s.diff}
+		index := make([][]int, height)
+		for y := 0; y < height; y++ {
+			work.data[y] = append([]T(nil), s.data[y]...)
+			work.bias[y] = make([]float64, width)
+			for x, b := range s.bias[y] {
+				// pixels to remove must not be duplicated
+				work.bias[y][x] = math.Abs(b)
+			}
+			index[y] = make([]int, width)
+			for x := range index[y] {
+				index[y][x] = x
+			}
+		}
+		picked := make([][]int, height)
+		for i := 0; i < batch; i++ {
+			seam := work.findSeam()
+			for y, x := range seam {
+				picked[y] = append(picked[y], index[y][x])
+			}
+			removeSeam(work.data, seam)
+			removeSeam(work.bias, seam)
+			removeSeam(index, seam)
+		}
+
+		for y := 0; y < height; y++ {
+			sort.Ints(picked[y])
+			row := make([]T, 0, width+batch)
+			bias := make([]float64, 0, width+batch)
+			k := 0
+			for x := 0; x < width; x++ {
+				row = append(row, s.data[y][x])
+				bias = append(bias, s.bias[y][x])
+				for k < len(picked[y]) && picked[y][k] == x {
+					next := x + 1
+					if next == width {
+						next = x
+					}
+					row = append(row, s.mix(s.data[y][x], s.data[y][next]))
+					bias = append(bias, s.bias[y][x])
+					k++
+				}
+			}
+			s.data[y] = row
+			s.bias[y] = bias
+		}
+		n -= batch
+	}
+}
+
+
+// resize carves or inserts seams until the image has the requested size,
+// columns first then rows.
+func (s *seamImage[T]) resize(newWidth, newHeight int) {
+	if w := s.width(); newWidth < w {
+		s.shrink(w - newWidth)
+	} else if newWidth > w {
+		s.grow(newWidth - w)
+	}
+	s.transpose()
+	if h := s.width(); newHeight < h {
+		s.shrink(h - newHeight)
+	} else if newHeight > h {
+		s.grow(newHeight - h)
+	}
+	s.transpose()
+}
+
+
+// SeamCarve resizes the graymap to newWidth x newHeight by removing or
+// inserting the seams of lowest energy, so that the detailed parts of the
+// image keep their shape. An object marked in opts.Remove can be erased by
+// shrinking past its size, then growing back.
+func (pgm *PGM) SeamCarve(newWidth, newHeight int, opts SeamCarveOptions) error {
+	if newWidth <= 0 || newHeight <= 0 {
+		return errors.New("invalid dimensions for resizing")
+	}
+	s, err := newSeamImage(pgm.data, opts,
+		func(a, b uint8) float64 {
+			return math.Abs(float64(a) - float64(b))
+		},
+		func(a, b uint8) uint8 {
+			return uint8((int(a) + int(b) + 1) / 2)
+		})
+	if err != nil {
+		return err
+	}
+	s.resize(newWidth, newHeight)
+	pgm.data = s.data
+	pgm.width = newWidth
+	pgm.height = newHeight
+	return nil
+}
+
+
+// SeamCarve resizes the pixmap to newWidth x newHeight by removing or
+// inserting the seams of lowest energy. See PGM.SeamCarve.
+func (ppm *PPM) SeamCarve(newWidth, newHeight int, opts SeamCarveOptions) error {
+	if newWidth <= 0 || newHeight <= 0 {
+		return errors.New("invalid dimensions for resizing")
+	}
+	s, err := newSeamImage(ppm.data, opts,
+		func(a, b Pixel) float64 {
+			return math.Abs(float64(a.R)-float64(b.R)) + math.Abs(float64(a.G)-float64(b.G)) + math.Abs(float64(a.B)-float64(b.B))
+		},
+		func(a, b Pixel) Pixel {
+			return Pixel{
+				uint8((int(a.R) + int(b.R) + 1) / 2),
+				uint8((int(a.G) + int(b.G) + 1) / 2),
+				uint8((int(a.B) + int(b.B) + 1) / 2),
+			}
+		})
+	if err != nil {
+		return err
+	}
+	s.resize(newWidth, newHeight)
+	ppm.data = s.data
+	ppm.width = newWidth
+	ppm.height = newHeight
+	return nil
+}
@@ -0,0 +1,253 @@
+package Netpbm
+
+import (
+	"math"
+	"sort"
+)
+
+// far stands for an infinite squared distance in the distance transform
+const far = 1e20
+
+
+// distance1D returns the squared distance transform of a line of squared
+// distances, the lower envelope of the parabolas rooted at every sample
+// (Felzenszwalb and Huttenlocher)
+func distance1D(f []float64) []float64 {
+	n := len(f)
+	d := make([]float64, n)
+	if n == 0 {
+		return d
+	}
+	v := make([]int, n)       // roots of the parabolas of the envelope
+	z := make([]float64, n+1) // boundaries between them
+	k := 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < n; q++ {
+		for {
+			s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+			if s > z[k] {
+				k++
+				v[k] = q
+				z[k] = s
+				z[k+1] = math.Inf(1)
+				break
+			}
+			if k == 0 {
+				v[0] = q
+				z[1] = math.Inf(1)
+				break
+			}
+			k--
+		}
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		d[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+	}
+	return d
+}
+
+
+// DistanceMap returns the Euclidean distance from every true pixel of the
+// bitmap to the nearest false pixel, false pixels being at 0. The outside of
+// the image does not count as false, so a bitmap with no false pixel is at
+// +Inf everywhere.
+func (pbm *PBM) DistanceMap() [][]float64 {
+	dist := make([][]float64, pbm.height)
+	for y := range dist {
+		dist[y] = make([]float64, pbm.width)
+		for x := range dist[y] {
+			if pbm.data[y][x] {
+				dist[y][x] = far
+			}
+		}
+	}
+	column := make([]float64, pbm.height)
+	for x := 0; x < pbm.width; x++ {
+		for y := range column {
+			column[y] = dist[y][x]
+		}
+		for y, v := range distance1D(column) {
+			dist[y][x] = v
+		}
+	}
+	for y := range dist {
+		dist[y] = distance1D(dist[y])
+		for x, v := range dist[y] {
+			if v >= far {
+				dist[y][x] = math.Inf(1)
+			} else {
+				dist[y][x] = math.Sqrt(v)
+			}
+		}
+	}
+	return dist
+}
+
+
+// DistanceTransform returns the distance map of the bitmap as a graymap, every
+// sample being the rounded distance in pixels. The maxval is the largest
+// distance, capped at 255 like the samples.
+func (pbm *PBM) DistanceTransform() *PGM {
+	dist := pbm.DistanceMap()
+	top := 1.0
+	for _, row := range dist {
+		for _, v := range row {
+			top = math.Max(top, math.Round(v))
+		}
+	}
+	pgm := NewPGM(pbm.width, pbm.height, int(math.Min(top, 255)))
+	pgm.setPlane(dist)
+	return pgm
+}
+
+
+// Thin reduces the true regions of the bitmap to lines one pixel wide with the
+// Zhang-Suen algorithm, keeping their connectivity and topology.
+func (pbm *PBM) Thin() {
+	at := func(x, y int) bool {
+		return x >= 0 && x < pbm.width && y >= 0 && y < pbm.height && pbm.data[y][x]
+	}
+	// neighbours P2 to P9, clockwise from the one above
+	around := []Point{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+	var p [8]bool
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			remove := make([]Point, 0)
+			for y := 0; y < pbm.height; y++ {
+				for x := 0; x < pbm.width; x++ {
+					if !pbm.data[y][x] {
+						continue
+					}
+					count, transitions := 0, 0
+					for i, d := range around {
+						p[i] = at(x+d.X, y+d.Y)
+					}
+					for i := range p {
+						if p[i] {
+							count++
+						}
+						if !p[i] && p[(i+1)%8] {
+							transitions++
+						}
+					}
+					if count < 2 || count > 6 || transitions != 1 {
+						continue
+					}
+					// the first step removes south-east boundary pixels and
+					// north-west corners, the second the opposite
+					if step == 0 && (p[0] && p[2] && p[4] || p[2] && p[4] && p[6]) {
+						continue
+					}
+					if step == 1 && (p[0] && p[2] && p[6] || p[0] && p[4] && p[6]) {
+						continue
+					}
+					remove = append(remove, Point{x, y})
+				}
+			}
+			for _, r := range remove {
+				pbm.data[r.Y][r.X] = false
+			}
+			changed = changed || len(remove) > 0
+		}
+	}
+}
+
+
+// MedialAxis reduces the bitmap to its medial axis, the centres of the largest
+// disks that fit in its true regions, and returns the distance map of the
+// original bitmap: along the axis, twice the distance is the stroke width.
+// Unlike Thin, the axis is not always connected.
+func (pbm *PBM) MedialAxis() [][]float64 {
+	dist := pbm.DistanceMap()
+	axis := make([][]bool, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		axis[y] = make([]bool, pbm.width)
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] {
+				continue
+			}
+			// the disk of a neighbour holds this one when it is larger by at
+			// least the distance between them
+			axis[y][x] = true
+			for dy := -1; dy <= 1 && axis[y][x]; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if (dx == 0 && dy == 0) || nx < 0 || nx >= pbm.width || ny < 0 || ny >= pbm.height {
+						continue
+					}
+					if dist[ny][nx]-dist[y][x] >= math.Hypot(float64(dx), float64(dy))-1e-9 {
+						axis[y][x] = false
+						break
+					}
+				}
+			}
+		}
+	}
+	pbm.data = axis
+	return dist
+}
+
+
+// cross is the z component of the cross product of o->a and o->b
+func cross(o, a, b Point) int {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+
+// ConvexHull returns the corners of the smallest convex polygon holding the
+// centres of the true pixels of the bitmap, clockwise on screen from the
+// top-left one. Points on its sides are left out. It is empty when there is no
+// true pixel.
+func (pbm *PBM) ConvexHull() []Point {
+	// only the ends of every row can be on the hull
+	points := make([]Point, 0)
+	for y := 0; y < pbm.height; y++ {
+		first, last := -1, -1
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				if first < 0 {
+					first = x
+				}
+				last = x
+			}
+		}
+		if first >= 0 {
+			points = append(points, Point{first, y})
+			if last != first {
+				points = append(points, Point{last, y})
+			}
+		}
+	}
+	if len(points) < 3 {
+		return points
+	}
+
+	// Andrew's monotone chain, ordered by y then x
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+	hull := make([]Point, 0, 2*len(points))
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
@@ -0,0 +1,250 @@
+package Netpbm
+
+import "math"
+
+// ThresholdMethod selects how Binarize picks the threshold.
+type ThresholdMethod int
+
+const (
+	ThresholdOtsu     ThresholdMethod = iota // global, maximises the variance between the two classes
+	ThresholdTriangle                        // global, suited to histograms with one main peak
+	ThresholdMean                            // local mean of the window minus C
+	ThresholdGaussian                        // Gaussian weighted local mean minus C
+	ThresholdNiblack                         // local mean + K * local deviation
+	ThresholdSauvola                         // local mean * (1 + K * (deviation / R - 1))
+)
+
+
+// ThresholdOptions configures Binarize. Local methods compare every pixel to a
+// threshold computed on the Window x Window area around it.
+type ThresholdOptions struct {
+	Method ThresholdMethod
+	Window int         // side of the local window, 15 when 0
+	C      float64     // offset of the mean and Gaussian methods, in samples
+	K      float64     // Niblack and Sauvola factor, -0.2 and 0.5 when 0
+	R      float64     // Sauvola dynamic range of the deviation, half the maxval when 0
+	Gray   GrayOptions // conversion used by PPM.Binarize
+}
+
+
+// otsuThreshold returns the level t splitting the histogram into [0, t] and
+// ]t, max] with the largest variance between the two classes
+func otsuThreshold(counts []int) int {
+	total, sum := 0, 0.0
+	for v, c := range counts {
+		total += c
+		sum += float64(v * c)
+	}
+	best, bestVariance := 0, -1.0
+	weight, partial := 0, 0.0
+	for t := 0; t < len(counts)-1; t++ {
+		weight += counts[t]
+		partial += float64(t * counts[t])
+		if weight == 0 || weight == total {
+			continue
+		}
+		w0, w1 := float64(weight), float64(total-weight)
+		m0, m1 := partial/w0, (sum-partial)/w1
+		if variance := w0 * w1 * (m0 - m1) * (m0 - m1); variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
+
+
+// triangleThreshold draws a line from the highest bin of the histogram to the
+// far end of its longest tail, and returns the level furthest from that line
+func triangleThreshold(counts []int) int {
+	first, last, peak := -1, 0, 0
+	for v, c := range counts {
+		if c > 0 {
+			if first < 0 {
+				first = v
+			}
+			last = v
+		}
+		if c > counts[peak] {
+			peak = v
+		}
+	}
+	if first < 0 || first == last {
+		return peak
+	}
+
+	// the tail is taken on the side of the peak holding the most levels
+	end, dir := last, 1
+	if peak-first > last-peak {
+		end, dir = first, -1
+	}
+	dx, dy := float64(end-peak), float64(counts[end]-counts[peak])
+	norm := math.Hypot(dx, dy)
+	best, bestDistance := peak, -1.0
+	for v := peak; v != end+dir; v += dir {
+		// distance to the line, up to the constant norm
+		d := math.Abs(dy*float64(v-peak)-dx*float64(counts[v]-counts[peak])) / norm
+		if d > bestDistance {
+			best, bestDistance = v, d
+		}
+	}
+	if dir < 0 {
+		// levels below the threshold are the dark class, keep the tail in it
+		best--
+	}
+	return best
+}
+
+
+// integral returns the summed-area tables of values and of their squares, one
+// row and column larger than the image
+func integral(values [][]float64) ([][]float64, [][]float64) {
+	height := len(values)
+	width := 0
+	if height > 0 {
+		width = len(values[0])
+	}
+	sum := make([][]float64, height+1)
+	squares := make([][]float64, height+1)
+	for y := range sum {
+		sum[y] = make([]float64, width+1)
+		squares[y] = make([]float64, width+1)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := values[y][x]
+			sum[y+1][x+1] = v + sum[y][x+1] + sum[y+1][x] - sum[y][x]
+			squares[y+1][x+1] = v*v + squares[y][x+1] + squares[y+1][x] - squares[y][x]
+		}
+	}
+	return sum, squares
+}
+
+
+// gaussianSmooth blurs values with a Gaussian of the given sigma, the kernel
+// being cut at radius and the edges repeated
+func gaussianSmooth(values [][]float64, sigma float64, radius int) [][]float64 {
+	k := gaussianKernel(sigma, radius)
+	return convolveSeparable(values, k, k, BorderClamp)
+}
+
+
+// binarize thresholds gray values going from 0 to max. It returns the bitmap,
+// true pixels being above the threshold, and the global threshold or -1 for
+// local methods.
+func binarize(values [][]float64, max int, opts ThresholdOptions) ([][]bool, int) {
+	height := len(values)
+	width := 0
+	if height > 0 {
+		width = len(values[0])
+	}
+	out := make([][]bool, height)
+	for y := range out {
+		out[y] = make([]bool, width)
+	}
+
+	if opts.Method == ThresholdOtsu || opts.Method == ThresholdTriangle {
+		counts := make([]int, max+1)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				counts[int(values[y][x])]++
+			}
+		}
+		t := otsuThreshold(counts)
+		if opts.Method == ThresholdTriangle {
+			t = triangleThreshold(counts)
+		}
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				out[y][x] = int(values[y][x]) > t
+			}
+		}
+		return out, t
+	}
+
+	window := opts.Window
+	if window <= 0 {
+		window = 15
+	}
+	r := window / 2
+	var smooth [][]float64
+	if opts.Method == ThresholdGaussian {
+		smooth = gaussianSmooth(values, float64(window)/6, r)
+	}
+	k := opts.K
+	if k == 0 {
+		k = 0.5
+		if opts.Method == ThresholdNiblack {
+			k = -0.2
+		}
+	}
+	dynamic := opts.R
+	if dynamic == 0 {
+		dynamic = float64(max) / 2
+	}
+	sum, squares := integral(values)
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y-r, y+r+1
+		if y0 < 0 {
+			y0 = 0
+		}
+		if y1 > height {
+			y1 = height
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x-r, x+r+1
+			if x0 < 0 {
+				x0 = 0
+			}
+			if x1 > width {
+				x1 = width
+			}
+			n := float64((x1 - x0) * (y1 - y0))
+			mean := (sum[y1][x1] - sum[y0][x1] - sum[y1][x0] + sum[y0][x0]) / n
+			variance := (squares[y1][x1]-squares[y0][x1]-squares[y1][x0]+squares[y0][x0])/n - mean*mean
+			deviation := math.Sqrt(math.Max(variance, 0))
+
+			var t float64
+			switch opts.Method {
+			case ThresholdGaussian:
+				t = smooth[y][x] - opts.C
+			case ThresholdNiblack:
+				t = mean + k*deviation
+			case ThresholdSauvola:
+				t = mean * (1 + k*(deviation/dynamic-1))
+			default:
+				t = mean - opts.C
+			}
+			out[y][x] = values[y][x] > t
+		}
+	}
+	return out, -1
+}
+
+
+// Binarize converts the graymap to a bitmap with an automatic threshold, true
+// pixels being the bright ones. It also returns the threshold chosen by the
+// global methods (pixels above it are true), or -1 for the local ones.
+func (pgm *PGM) Binarize(opts ThresholdOptions) (*PBM, int) {
+	values := make([][]float64, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		values[y] = make([]float64, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			values[y][x] = float64(pgm.data[y][x])
+		}
+	}
+	data, t := binarize(values, pgm.max, opts)
+	return &PBM{
+		data:        data,
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P1",
+	}, t
+}
+
+
+// Binarize converts the pixmap to gray with opts.Gray, then to a bitmap with an
+// automatic threshold. See PGM.Binarize.
+func (ppm *PPM) Binarize(opts ThresholdOptions) (*PBM, int) {
+	return ppm.ToPGM(opts.Gray).Binarize(opts)
+}
@@ -0,0 +1,244 @@
+package Netpbm
+
+import (
+	"errors"
+	"math"
+)
+
+// Tile is a piece cut from a larger image, At being where its top-left corner
+// was in that image.
+type Tile[T Image] struct {
+	Image T
+	At    Point
+}
+
+
+// tileStarts returns where tiles of length tile start along an axis of length
+// size. The last tile is moved back so it ends on the edge instead of sticking
+// out, which makes it overlap its neighbour a bit more.
+func tileStarts(size, tile, overlap int) []int {
+	if size <= tile {
+		return []int{0}
+	}
+	starts := make([]int, 0)
+	for s := 0; ; s += tile - overlap {
+		if s+tile >= size {
+			starts = append(starts, size-tile)
+			return starts
+		}
+		starts = append(starts, s)
+	}
+}
+
+
+// tileGrid returns the areas of all the tiles, row by row
+func tileGrid(width, height, tileWidth, tileHeight, overlap int) ([]Rectangle, error) {
+	if tileWidth <= 0 || tileHeight <= 0 {
+		return nil, errors.New("invalid tile size")
+	}
+	if overlap < 0 || overlap >= tileWidth || overlap >= tileHeight {
+		return nil, errors.New("overlap must be smaller than the tiles")
+	}
+	rects := make([]Rectangle, 0)
+	for _, y := range tileStarts(height, tileHeight, overlap) {
+		for _, x := range tileStarts(width, tileWidth, overlap) {
+			rects = append(rects, Rectangle{x, y, tileWidth, tileHeight}.clip(width, height))
+		}
+	}
+	return rects, nil
+}
+
+
+// Tiles cuts the bitmap into tiles of the given size, neighbours sharing overlap
+// pixels. Tiles are returned row by row and always lie fully inside the image,
+// unless the image is smaller than a tile.
+func (pbm *PBM) Tiles(tileWidth, tileHeight, overlap int) ([]Tile[*PBM], error) {
+	rects, err := tileGrid(pbm.width, pbm.height, tileWidth, tileHeight, overlap)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]Tile[*PBM], len(rects))
+	for i, r := range rects {
+		tiles[i] = Tile[*PBM]{pbm.SubImage(r), Point{r.X, r.Y}}
+	}
+	return tiles, nil
+}
+
+
+// Tiles cuts the graymap into tiles of the given size, neighbours sharing
+// overlap pixels. See PBM.Tiles.
+func (pgm *PGM) Tiles(tileWidth, tileHeight, overlap int) ([]Tile[*PGM], error) {
+	rects, err := tileGrid(pgm.width, pgm.height, tileWidth, tileHeight, overlap)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]Tile[*PGM], len(rects))
+	for i, r := range rects {
+		tiles[i] = Tile[*PGM]{pgm.SubImage(r), Point{r.X, r.Y}}
+	}
+	return tiles, nil
+}
+
+
+// Tiles cuts the pixmap into tiles of the given size, neighbours sharing
+// overlap pixels. See PBM.Tiles.
+func (ppm *PPM) Tiles(tileWidth, tileHeight, overlap int) ([]Tile[*PPM], error) {
+	rects, err := tileGrid(ppm.width, ppm.height, tileWidth, tileHeight, overlap)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]Tile[*PPM], len(rects))
+	for i, r := range rects {
+		tiles[i] = Tile[*PPM]{ppm.SubImage(r), Point{r.X, r.Y}}
+	}
+	return tiles, nil
+}
+
+
+// stitchSize returns the size of the image covered by tiles
+func stitchSize[T Image](tiles []Tile[T]) (int, int) {
+	width, height := 0, 0
+	for _, t := range tiles {
+		w, h := t.Image.Size()
+		if t.At.X+w > width {
+			width = t.At.X + w
+		}
+		if t.At.Y+h > height {
+			height = t.At.Y + h
+		}
+	}
+	return width, height
+}
+
+
+// blendWeight is the weight of pixel (x, y) of a w*h tile when tiles overlap.
+// It decreases linearly towards the tile borders, so overlapping tiles fade
+// into each other.
+func blendWeight(x, y, w, h int) float64 {
+	wx := math.Min(float64(x+1), float64(w-x))
+	wy := math.Min(float64(y+1), float64(h-y))
+	return wx * wy
+}
+
+
+// StitchPBM puts tiles back together. Where tiles overlap, each pixel takes the
+// value with the largest blending weight.
+func StitchPBM(tiles []Tile[*PBM]) *PBM {
+	width, height := stitchSize(tiles)
+	sum := make([][]float64, height)
+	total := make([][]float64, height)
+	for y := range sum {
+		sum[y] = make([]float64, width)
+		total[y] = make([]float64, width)
+	}
+	for _, t := range tiles {
+		for y := 0; y < t.Image.height; y++ {
+			for x := 0; x < t.Image.width; x++ {
+				w := blendWeight(x, y, t.Image.width, t.Image.height)
+				if t.Image.data[y][x] {
+					sum[t.At.Y+y][t.At.X+x] += w
+				}
+				total[t.At.Y+y][t.At.X+x] += w
+			}
+		}
+	}
+
+	pbm := NewPBM(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pbm.data[y][x] = total[y][x] > 0 && sum[y][x]*2 >= total[y][x]
+		}
+	}
+	return pbm
+}
+
+
+// StitchPGM puts tiles back together, blending them where they overlap.
+// The result takes the largest maxval of the tiles.
+func StitchPGM(tiles []Tile[*PGM]) *PGM {
+	width, height := stitchSize(tiles)
+	max := 0
+	for _, t := range tiles {
+		if t.Image.max > max {
+			max = t.Image.max
+		}
+	}
+	sum := make([][]float64, height)
+	total := make([][]float64, height)
+	for y := range sum {
+		sum[y] = make([]float64, width)
+		total[y] = make([]float64, width)
+	}
+	for _, t := range tiles {
+		// a maxval of 0 only holds black, which needs no rescaling
+		scale := 1.0
+		if t.Image.max > 0 {
+			scale = float64(max) / float64(t.Image.max)
+		}
+		for y := 0; y < t.Image.height; y++ {
+			for x := 0; x < t.Image.width; x++ {
+				w := blendWeight(x, y, t.Image.width, t.Image.height)
+				sum[t.At.Y+y][t.At.X+x] += w * float64(t.Image.data[y][x]) * scale
+				total[t.At.Y+y][t.At.X+x] += w
+			}
+		}
+	}
+
+	pgm := NewPGM(width, height, max)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if total[y][x] > 0 {
+				pgm.data[y][x] = uint8(math.Round(sum[y][x] / total[y][x]))
+			}
+		}
+	}
+	return pgm
+}
+
+
+// StitchPPM puts tiles back together, blending them where they overlap.
+// The result takes the largest maxval of the tiles.
+func StitchPPM(tiles []Tile[*PPM]) *PPM {
+	width, height := stitchSize(tiles)
+	max := 0
+	for _, t := range tiles {
+		if t.Image.max > max {
+			max = t.Image.max
+		}
+	}
+	sum := make([][][3]float64, height)
+	total := make([][]float64, height)
+	for y := range sum {
+		sum[y] = make([][3]float64, width)
+		total[y] = make([]float64, width)
+	}
+	for _, t := range tiles {
+		// a maxval of 0 only holds black, which needs no rescaling
+		scale := 1.0
+		if t.Image.max > 0 {
+			scale = float64(max) / float64(t.Image.max)
+		}
+		for y := 0; y < t.Image.height; y++ {
+			for x := 0; x < t.Image.width; x++ {
+				w := blendWeight(x, y, t.Image.width, t.Image.height)
+				p := t.Image.data[y][x]
+				s := &sum[t.At.Y+y][t.At.X+x]
+				s[0] += w * float64(p.R) * scale
+				s[1] += w * float64(p.G) * scale
+				s[2] += w * float64(p.B) * scale
+				total[t.At.Y+y][t.At.X+x] += w
+			}
+		}
+	}
+
+	ppm := NewPPM(width, height, max)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if t := total[y][x]; t > 0 {
+				s := sum[y][x]
+				ppm.data[y][x] = Pixel{uint8(math.Round(s[0] / t)), uint8(math.Round(s[1] / t)), uint8(math.Round(s[2] / t))}
+			}
+		}
+	}
+	return ppm
+}
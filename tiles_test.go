@@ -0,0 +1,41 @@
+package Netpbm
+
+import "testing"
+
+func TestStitch(t *testing.T) {
+	pgm := NewPGM(10, 7, 255)
+	for y := range pgm.data {
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = uint8(x*20 + y)
+		}
+	}
+	tiles, err := pgm.Tiles(4, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stitched := StitchPGM(tiles)
+	for y := range pgm.data {
+		for x := range pgm.data[y] {
+			if stitched.data[y][x] != pgm.data[y][x] {
+				t.Fatalf("pixel (%d, %d) is %d, want %d", x, y, stitched.data[y][x], pgm.data[y][x])
+			}
+		}
+	}
+
+	// a tile of maxval 0 is black and must not spoil its neighbours
+	black := NewPGM(2, 2, 0)
+	white := NewPGM(2, 2, 255)
+	white.fill(255)
+	gray := StitchPGM([]Tile[*PGM]{{black, Point{0, 0}}, {white, Point{1, 0}}})
+	if gray.max != 255 || gray.data[0][0] != 0 || gray.data[0][1] != 128 || gray.data[0][2] != 255 {
+		t.Fatalf("got maxval %d and rows %v", gray.max, gray.data)
+	}
+	color := StitchPPM([]Tile[*PPM]{{NewPPM(2, 2, 0), Point{0, 0}}, {black.ToPPM(), Point{1, 0}}})
+	for _, row := range color.data {
+		for _, p := range row {
+			if p != (Pixel{}) {
+				t.Fatalf("got %v, want black", color.data)
+			}
+		}
+	}
+}